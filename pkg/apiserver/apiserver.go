@@ -1,17 +1,27 @@
 package apiserver
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/emicklei/go-restful"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/apiserver/pkg/endpoints/discovery"
 	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/apiserver/pkg/server/healthz"
 
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 
@@ -30,7 +40,26 @@ var (
 )
 
 func addKnownTypes(scheme *runtime.Scheme) error {
-	scheme.AddKnownTypes(SchemeGroupVersion)
+	scheme.AddKnownTypes(SchemeGroupVersion,
+		&ProvenanceVersionList{},
+		&SpecHistory{},
+		&ProvenanceObjectVersion{},
+		&SpecDiff{},
+		&BisectResult{},
+		&BisectTransitions{},
+		&BisectExplanation{},
+		&FieldHistory{},
+		&RollbackPatch{},
+		&FieldManagerHistory{},
+		&SubresourceEventList{},
+		&ProvenanceObject{},
+		&ProvenanceObjectList{},
+		&IngestionStatus{},
+		&DeadLetterList{},
+		&DeadLetterCount{},
+		&ReprocessResult{},
+		&DiscoveredKindList{},
+	)
 	return nil
 }
 
@@ -57,13 +86,37 @@ func init() {
 		&metav1.APIGroup{},
 		&metav1.APIResourceList{},
 	)
-
-	// Start collecting provenance
-	go provenance.CollectProvenance()
 }
 
+// Where the audit events come from
+const (
+	// follow the audit log of the kube-apiserver
+	SourceFile = "file"
+	// read the static sample audit log once, for clusters without audit logging
+	SourceSample = "sample"
+	// receive events from the audit webhook backend of the kube-apiserver
+	SourceWebhook = "webhook"
+)
+
 type ExtraConfig struct {
-	// Place you custom config here.
+	Source        string
+	AuditLogPath  string
+	SampleLogPath string
+	// yaml file with the kinds to track, see kind_compositions.yaml
+	KindCompositionFile string
+	PollInterval        time.Duration
+	// learn the tracked kinds from the audit events, in addition to the
+	// kind compositions file
+	DiscoverKinds bool
+	// the ingestion health check fails when the audit log was not read
+	// successfully for longer than this
+	StallThreshold time.Duration
+	// kinds, plurals or plural.group names to discover, all if empty
+	IncludeKinds []string
+	ExcludeKinds []string
+	// salt of the hashes of redacted values, a random one that lives as
+	// long as the process if empty
+	RedactionSalt string
 }
 
 type Config struct {
@@ -74,6 +127,13 @@ type Config struct {
 // ProvenanceServer contains state for a Kubernetes cluster master/api server.
 type ProvenanceServer struct {
 	GenericAPIServer *genericapiserver.GenericAPIServer
+	Collector        *provenance.Collector
+
+	// serializes the changes of activeKinds
+	activeKindsLock sync.Mutex
+	// plural -> activeKind, for the kinds whose routes answer requests. A
+	// map[string]activeKind that is replaced when the tracked kinds change.
+	activeKinds atomic.Value
 }
 
 type completedConfig struct {
@@ -111,49 +171,332 @@ func (c completedConfig) New() (*ProvenanceServer, error) {
 	s := &ProvenanceServer{
 		GenericAPIServer: genericServer,
 	}
+	switch c.ExtraConfig.Source {
+	case SourceFile:
+		s.Collector = provenance.NewCollector(c.ExtraConfig.AuditLogPath, c.ExtraConfig.PollInterval, false)
+	case SourceSample:
+		s.Collector = provenance.NewCollector(c.ExtraConfig.SampleLogPath, c.ExtraConfig.PollInterval, true)
+	}
 
-	apiGroupInfo := genericapiserver.NewDefaultAPIGroupInfo(GroupName, Scheme, metav1.ParameterCodec, Codecs)
+	RegisterMetrics()
 
-	if err := s.GenericAPIServer.InstallAPIGroup(&apiGroupInfo); err != nil {
-		return nil, err
+	if c.ExtraConfig.RedactionSalt != "" {
+		provenance.SetRedactionSalt([]byte(c.ExtraConfig.RedactionSalt))
+	}
+	installGroupDiscovery(s)
+
+	if c.ExtraConfig.KindCompositionFile != "" {
+		if err := provenance.ReadKindCompositionFile(c.ExtraConfig.KindCompositionFile); err != nil {
+			return nil, err
+		}
+	}
+	if c.ExtraConfig.DiscoverKinds {
+		provenance.EnableKindDiscovery(c.ExtraConfig.IncludeKinds, c.ExtraConfig.ExcludeKinds, s.syncActiveKinds)
+	}
+	s.syncActiveKinds()
+	s.GenericAPIServer.Handler.GoRestfulContainer.Add(s.kindWebService())
+	installDiagnosticsWebService(s)
+
+	if c.ExtraConfig.KindCompositionFile != "" {
+		// Pick up changes of the kind compositions without a restart
+		watcher := provenance.NewCompositionWatcher(c.ExtraConfig.KindCompositionFile, c.ExtraConfig.PollInterval,
+			func(old, new map[string]string) {
+				s.syncActiveKinds()
+			})
+		err = s.GenericAPIServer.AddPostStartHook("watch-kind-compositions",
+			func(hookContext genericapiserver.PostStartHookContext) error {
+				go watcher.Run(stopContext(hookContext.StopCh))
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s.Collector == nil {
+		installAuditWebhookWebService(s)
+		return s, nil
 	}
 
-	installCompositionProvenanceWebService(s)
+	// /healthz/provenance-collector fails when the collector has stopped,
+	// /healthz/provenance-ingestion also when it has not read the log for
+	// longer than the stall threshold
+	err = s.GenericAPIServer.AddHealthzChecks(
+		healthz.NamedCheck("provenance-collector", func(r *http.Request) error {
+			return s.Collector.CheckRunning()
+		}),
+		healthz.NamedCheck("provenance-ingestion", func(r *http.Request) error {
+			return s.Collector.CheckIngestion(c.ExtraConfig.StallThreshold)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	installIngestionWebService(s)
+
+	// Collect provenance once the server is serving, until it is stopped
+	err = s.GenericAPIServer.AddPostStartHook("start-provenance-collector",
+		func(hookContext genericapiserver.PostStartHookContext) error {
+			s.Collector.Start(stopContext(hookContext.StopCh))
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
 
 	return s, nil
 }
 
-func installCompositionProvenanceWebService(provenanceServer *ProvenanceServer) {
-	for _, resourceKindPlural := range provenance.KindPluralMap {
-		namespaceToUse := provenance.Namespace
-		path := "/apis/" + GroupName + "/" + GroupVersion + "/namespaces/"
-		path = path + namespaceToUse + "/" + strings.ToLower(resourceKindPlural)
-		fmt.Println("WS PATH:" + path)
+// Returns a context that is cancelled when stopCh is closed.
+func stopContext(stopCh <-chan struct{}) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+	return ctx
+}
+
+// A tracked kind, as the routes and the discovery document see it
+type activeKind struct {
+	Kind string
+	// plural of the kind, the provenance package knows its objects by it
+	Plural        string
+	ClusterScoped bool
+	ShortNames    []string
+}
+
+// provenanceResource is the name of the provenance resource of kind in the
+// discovery document, e.g. postgresprovenances. It must not be the plural
+// of the kind, kubectl would then resolve the kind's own resource to this
+// group and list provenance instead of the objects.
+func provenanceResource(kind string) string {
+	return strings.ToLower(kind) + "provenances"
+}
+
+// syncActiveKinds makes the kind routes answer for the tracked kinds. The
+// set of active kinds is replaced in one step, so a request sees either all
+// of the old kinds or all of the new ones.
+func (s *ProvenanceServer) syncActiveKinds() {
+	s.activeKindsLock.Lock()
+	defer s.activeKindsLock.Unlock()
+	// read under activeKindsLock, so concurrent syncs apply the kinds in order
+	kinds := provenance.TrackedKinds()
+	clusterScoped := provenance.ClusterScopedKinds()
+	shortNames := provenance.KindShortNames()
+
+	// the routes answer for the provenance resource of a kind, and for its
+	// plural as they did before the resources were published
+	active := make(map[string]activeKind)
+	for kind, resourceKindPlural := range kinds {
+		active[provenanceResource(kind)] = activeKind{
+			Kind:          kind,
+			Plural:        strings.ToLower(resourceKindPlural),
+			ClusterScoped: clusterScoped[kind],
+			ShortNames:    shortNames[kind],
+		}
+	}
+	for _, kind := range active {
+		if kind.Plural == provenanceResource(kind.Kind) {
+			continue
+		}
+		if ambiguousPlural(kind.Plural) {
+			fmt.Printf("Kind %s is only served as %s, its plural %s is also a path element of the object routes\n",
+				kind.Kind, provenanceResource(kind.Kind), kind.Plural)
+			continue
+		}
+		active[kind.Plural] = kind
+	}
+	s.activeKinds.Store(active)
+	fmt.Printf("Active kinds: %v\n", active)
+}
 
-		ws := getWebService()
-		ws.Path(path).
-			Consumes(restful.MIME_JSON, restful.MIME_XML).
-			Produces(restful.MIME_JSON, restful.MIME_XML)
-		getPath := "/{resource-id}/versions"
-		fmt.Println("Get Path:" + getPath)
-		ws.Route(ws.GET(getPath).To(getVersions))
+// ambiguousPlural reports whether plural can not be told apart from the
+// other path elements of the kind routes. With the Namespace kind, the list
+// of a kind whose plural is events, /namespaces/default/events, would be
+// the events of the namespace default, and the router would pick one of
+// them without telling. The provenance resources end in provenances and
+// never collide.
+func ambiguousPlural(plural string) bool {
+	if plural == "namespaces" {
+		return true
+	}
+	for _, route := range objectRoutes {
+		if route.subresource == plural {
+			return true
+		}
+	}
+	return false
+}
 
-		historyPath := "/{resource-id}/spechistory"
-		fmt.Println("History Path:" + historyPath)
-		ws.Route(ws.GET(historyPath).To(getHistory))
+func (s *ProvenanceServer) loadActiveKinds() map[string]activeKind {
+	active, _ := s.activeKinds.Load().(map[string]activeKind)
+	return active
+}
 
-		diffPath := "/{resource-id}/diff"
-		fmt.Println("Diff Path:" + diffPath)
-		ws.Route(ws.GET(diffPath).To(getDiff))
+// Answers with 404 for kinds that are not in the active configuration, and
+// for a namespaced kind asked for without namespace or the other way round.
+// The list of a namespaced kind may leave out the namespace, it then lists
+// all namespaces.
+func (s *ProvenanceServer) activeKindFilter(request *restful.Request, response *restful.Response, chain *restful.FilterChain) {
+	plural := request.PathParameter("plural")
+	kind, ok := s.loadActiveKinds()[plural]
+	namespace := request.PathParameter("namespace")
+	allNamespaces := request.PathParameter("resource-id") == ""
+	message := ""
+	switch {
+	case !ok:
+		message = "Kind " + plural + " is not tracked"
+	case kind.ClusterScoped && namespace != "":
+		message = "Kind " + plural + " is cluster scoped, its objects have no namespace"
+	case !kind.ClusterScoped && namespace == "" && !allNamespaces:
+		message = "Kind " + plural + " is namespaced, its objects are found under /namespaces/{namespace}"
+	}
+	if message != "" {
+		status := apierrors.NewNotFound(schema.GroupResource{Group: GroupName, Resource: plural}, "").ErrStatus
+		status.Message = message
+		writeStatus(request, response, status)
+		return
+	}
+	request.SetAttribute("plural", kind.Plural)
+	chain.ProcessFilter(request, response)
+}
 
-		bisectPath := "/{resource-id}/bisect"
-		fmt.Println("Bisect Path:" + bisectPath)
-		ws.Route(ws.GET(bisectPath).To(bisect))
+// The plural, namespace and name of the object a kind route is about. The
+// namespace is empty for cluster scoped kinds. The path may name the
+// provenance resource of the kind, activeKindFilter puts its plural aside.
+func objectOf(request *restful.Request) (string, string, string) {
+	plural, _ := request.Attribute("plural").(string)
+	if plural == "" {
+		plural = request.PathParameter("plural")
+	}
+	return plural, request.PathParameter("namespace"), request.PathParameter("resource-id")
+}
 
-		provenanceServer.GenericAPIServer.Handler.GoRestfulContainer.Add(ws)
+// The routes of an object, they are published as subresources of the
+// provenance resource of its kind
+var objectRoutes = []struct {
+	subresource string
+	// kind of the response
+	kind    string
+	handler restful.RouteFunction
+	// the query can also be sent as the body of a POST
+	post bool
+}{
+	{"versions", "ProvenanceVersionList", getVersions, false},
+	{"spechistory", "SpecHistory", getHistory, false},
+	{"version", "ProvenanceObjectVersion", getVersion, false},
+	{"diff", "SpecDiff", getDiff, false},
+	{"fieldhistory", "FieldHistory", getFieldHistory, false},
+	{"bisect", "BisectResult", bisect, true},
+	{"rollback", "RollbackPatch", getRollback, false},
+	{"fieldmanagers", "FieldManagerHistory", getFieldManagers, false},
+	{"statushistory", "SpecHistory", getStatusHistory, false},
+	{"events", "SubresourceEventList", getEvents, false},
+}
 
+// kindWebService serves the provenance of all kinds, and the discovery
+// document of the group version. The objects of a namespaced kind are found
+// under their namespace, those of a cluster scoped kind right under the
+// group version. The routes take the plural as a parameter, so a single web
+// service is enough, and the kinds can change without touching the routes.
+func (s *ProvenanceServer) kindWebService() *restful.WebService {
+	path := "/apis/" + GroupName + "/" + GroupVersion
+	fmt.Println("WS PATH:" + path)
+
+	ws := getWebService()
+	ws.Path(path).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON, mimeYAML, mimeText)
+	for _, kindPath := range []string{"/namespaces/{namespace}/{plural}", "/{plural}"} {
+		fmt.Println("List Path:" + kindPath)
+		ws.Route(ws.GET(kindPath).
+			Filter(s.activeKindFilter).
+			Filter(measureQuery("list")).
+			To(listObjects))
+
+		objectPath := kindPath + "/{resource-id}"
+		//a version as an object that can be applied again, it is not
+		//published in the discovery document
+		ws.Route(ws.GET(objectPath + "/versions/{version}").
+			Filter(s.activeKindFilter).
+			Filter(measureQuery("manifest")).
+			To(getManifest))
+		for _, route := range objectRoutes {
+			routePath := objectPath + "/" + route.subresource
+			fmt.Println("Path:" + routePath)
+			ws.Route(ws.GET(routePath).
+				Filter(s.activeKindFilter).
+				Filter(measureQuery(route.subresource)).
+				To(route.handler))
+			if route.post {
+				ws.Route(ws.POST(routePath).
+					Filter(s.activeKindFilter).
+					Filter(measureQuery(route.subresource)).
+					To(route.handler))
+			}
+		}
 	}
-	fmt.Println("Done registering.")
+	discovery.NewAPIVersionHandler(Codecs, SchemeGroupVersion, discovery.APIResourceListerFunc(s.listAPIResources)).AddToWebService(ws)
+
+	return ws
+}
+
+func installDiagnosticsWebService(provenanceServer *ProvenanceServer) {
+	path := "/apis/" + GroupName + "/" + GroupVersion + "/deadletters"
+	fmt.Println("WS PATH:" + path)
+
+	ws := getWebService()
+	ws.Path(path).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON, mimeYAML, mimeText)
+	ws.Route(ws.GET("").To(getDeadLetters))
+	ws.Route(ws.GET("/count").To(getDeadLetterCount))
+	ws.Route(ws.POST("/reprocess").To(reprocessDeadLetters))
+
+	provenanceServer.GenericAPIServer.Handler.GoRestfulContainer.Add(ws)
+
+	path = "/apis/" + GroupName + "/" + GroupVersion + "/discoveredkinds"
+	fmt.Println("WS PATH:" + path)
+
+	ws = getWebService()
+	ws.Path(path).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON, mimeYAML, mimeText)
+	ws.Route(ws.GET("").To(getDiscoveredKinds))
+
+	provenanceServer.GenericAPIServer.Handler.GoRestfulContainer.Add(ws)
+}
+
+func installIngestionWebService(provenanceServer *ProvenanceServer) {
+	path := "/apis/" + GroupName + "/" + GroupVersion + "/ingestion"
+	fmt.Println("WS PATH:" + path)
+
+	ws := getWebService()
+	ws.Path(path).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON, mimeYAML, mimeText)
+	ws.Route(ws.GET("").To(func(request *restful.Request, response *restful.Response) {
+		status := provenanceServer.Collector.Status()
+		writeObject(request, response, newIngestionStatus(status), status.String())
+	}))
+
+	provenanceServer.GenericAPIServer.Handler.GoRestfulContainer.Add(ws)
+}
+
+// The audit webhook backend posts batches of events as an audit EventList,
+// point its kubeconfig at this path.
+func installAuditWebhookWebService(provenanceServer *ProvenanceServer) {
+	path := "/apis/" + GroupName + "/" + GroupVersion + "/auditevents"
+	fmt.Println("WS PATH:" + path)
+
+	ws := getWebService()
+	ws.Path(path).
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON, restful.MIME_XML)
+	ws.Route(ws.POST("").To(receiveAuditEvents))
+
+	provenanceServer.GenericAPIServer.Handler.GoRestfulContainer.Add(ws)
 }
 
 func getWebService() *restful.WebService {
@@ -165,146 +508,464 @@ func getWebService() *restful.WebService {
 	return ws
 }
 
+// listObjects lists the tracked objects of a kind, in the namespace of the
+// path or in all namespaces. The optional labelSelector and namePrefix query
+// parameters narrow the list.
+func listObjects(request *restful.Request, response *restful.Response) {
+	fmt.Println("Inside listObjects")
+	resourcePlural, namespace, _ := objectOf(request)
+	selector, err := labels.Parse(request.QueryParameter("labelSelector"))
+	if err != nil {
+		writeError(request, response, newBadRequest(resourcePlural, "", "labelSelector", err.Error()), resourcePlural, "")
+		return
+	}
+	provenance.StoreLock.RLock()
+	defer provenance.StoreLock.RUnlock()
+	objects := provenance.ListProvenanceObjects(resourcePlural, namespace, request.QueryParameter("namePrefix"), selector)
+	writeObject(request, response, newObjectList(objects), provenance.ObjectListString(objects))
+}
+
 func getVersions(request *restful.Request, response *restful.Response) {
-	resourceName := request.PathParameter("resource-id")
-	requestPath := request.Request.URL.Path
-	resourcePathSlice := strings.Split(requestPath, "/")
-	resourceKind := resourcePathSlice[6] // Kind is 7th element in the slice
-	provenanceInfo := "Resource Name:" + resourceName + " Resource Kind: " + resourceKind + "\n"
-	response.Write([]byte(provenanceInfo))
-	intendedProvObj := provenance.FindProvenanceObjectByName(resourceName, provenance.AllProvenanceObjects)
-
-	//TODO: Validate request based on the correct namespace and the correct plural type.
-	//I have the namespace/pluralkind datain my ProvenanceOfObject struct so it is easy to make these changes later
+	resourcePlural, namespace, resourceName := objectOf(request)
+	provenanceInfo := "Resource Name:" + resourceName + " Resource Kind: " + resourcePlural + "\n"
+	provenance.StoreLock.RLock()
+	defer provenance.StoreLock.RUnlock()
+	intendedProvObj := provenance.FindProvenanceObject(resourcePlural, namespace, resourceName)
+
 	if intendedProvObj == nil {
-		s := fmt.Sprintf("Could not find any provenance history for resource name: %s", resourceName)
-		response.Write([]byte(s))
-	} else {
-		response.Write([]byte(intendedProvObj.ObjectFullHistory.GetVersions()))
+		writeError(request, response, newNotFound(resourcePlural, namespace, resourceName), resourcePlural, resourceName)
+		return
+	}
+	text := provenanceInfo + intendedProvObj.ObjectFullHistory.GetVersions()
+	writeObject(request, response, newVersionList(intendedProvObj), text)
+}
+
+func getManifest(request *restful.Request, response *restful.Response) {
+	resourcePlural, namespace, resourceName := objectOf(request)
+	provenance.StoreLock.RLock()
+	defer provenance.StoreLock.RUnlock()
+	intendedProvObj := provenance.FindProvenanceObject(resourcePlural, namespace, resourceName)
+	if intendedProvObj == nil {
+		writeError(request, response, newNotFound(resourcePlural, namespace, resourceName), resourcePlural, resourceName)
+		return
 	}
+	version, err := strconv.Atoi(request.PathParameter("version"))
+	if err != nil {
+		message := fmt.Sprintf("Could not parse version %s to int: %s", request.PathParameter("version"), err.Error())
+		writeError(request, response, newBadRequest(resourcePlural, resourceName, "version", message), resourcePlural, resourceName)
+		return
+	}
+	manifest, err := intendedProvObj.Manifest(version)
+	if err != nil {
+		writeError(request, response, err, resourcePlural, resourceName)
+		return
+	}
+	writeManifest(request, response, manifest)
 }
 
 func getHistory(request *restful.Request, response *restful.Response) {
 	fmt.Println("Inside gethistory")
-	resourceName := request.PathParameter("resource-id")
-	requestPath := request.Request.URL.Path
-	resourcePathSlice := strings.Split(requestPath, "/")
-	resourceKind := resourcePathSlice[6] // Kind is 7th element in the slice
-
-	provenanceInfo := "Resource Name:" + resourceName + " Resource Kind:" + resourceKind + "\n"
-	response.Write([]byte(provenanceInfo))
-	intendedProvObj := provenance.FindProvenanceObjectByName(resourceName, provenance.AllProvenanceObjects)
+	resourcePlural, namespace, resourceName := objectOf(request)
+
+	provenanceInfo := "Resource Name:" + resourceName + " Resource Kind:" + resourcePlural + "\n"
+	provenance.StoreLock.RLock()
+	defer provenance.StoreLock.RUnlock()
+	intendedProvObj := provenance.FindProvenanceObject(resourcePlural, namespace, resourceName)
 	//optional parameters
 	start := request.QueryParameter("start")
 	end := request.QueryParameter("end")
+	//asOf ends the history with the version in effect at that time
+	asOf := request.QueryParameter("asOf")
 
-	//TODO: Validate request based on the correct namespace and the correct plural type.
-	//I have the namespace/pluralkind datain my ProvenanceOfObject struct so it is easy to make these changes later
 	if intendedProvObj == nil {
-		s := fmt.Sprintf("Could not find any provenance history for resource name: %s", resourceName)
-		response.Write([]byte(s))
-	} else {
-		if start != "" && end != "" { //have both a start and an end
-			fmt.Printf("Start:%s", start)
-			fmt.Printf("End:%s", end)
-			startInt, err := strconv.Atoi(start)
-			if err != nil {
-				s := fmt.Sprintf("Could not parse start query parameter to int: %s", err.Error())
-				response.Write([]byte(s))
-				return
-			}
-			endInt, err := strconv.Atoi(end)
+		writeError(request, response, newNotFound(resourcePlural, namespace, resourceName), resourcePlural, resourceName)
+		return
+	}
+	if (start != "" && end != "") || asOf != "" { //have both a start and an end
+		fmt.Printf("Start:%s", start)
+		fmt.Printf("End:%s", end)
+		startInt := 0
+		if start != "" {
+			var err error
+			startInt, err = versionParameter(request, resourcePlural, resourceName, "start")
 			if err != nil {
-				s := fmt.Sprintf("Could not parse end query parameter to int: %s", err.Error())
-				response.Write([]byte(s))
+				writeError(request, response, err, resourcePlural, resourceName)
 				return
 			}
-			fmt.Printf("Spec history starting with version %d and ending with version %d", startInt, endInt)
-			response.Write([]byte(intendedProvObj.ObjectFullHistory.SpecHistoryInterval(startInt, endInt)))
-		} else { //start and end
-			response.Write([]byte(intendedProvObj.ObjectFullHistory.SpecHistory()))
 		}
+		endInt, err := versionOrTimeParameter(request, intendedProvObj.ObjectFullHistory, resourcePlural, resourceName, "end", "asOf")
+		if err != nil {
+			writeError(request, response, err, resourcePlural, resourceName)
+			return
+		}
+		fmt.Printf("Spec history starting with version %d and ending with version %d", startInt, endInt)
+		history, err := intendedProvObj.ObjectFullHistory.SpecHistoryInterval(startInt, endInt)
+		if err != nil {
+			writeError(request, response, err, resourcePlural, resourceName)
+			return
+		}
+		writeObject(request, response, newSpecHistory(intendedProvObj, intendedProvObj.ObjectFullHistory.SpecsInInterval(startInt, endInt)), provenanceInfo+history)
+	} else { //start and end
+		text := provenanceInfo + intendedProvObj.ObjectFullHistory.SpecHistory()
+		writeObject(request, response, newSpecHistory(intendedProvObj, intendedProvObj.ObjectFullHistory.SpecsInOrder()), text)
 	}
+}
 
+func getVersion(request *restful.Request, response *restful.Response) {
+	resourcePlural, namespace, resourceName := objectOf(request)
+	provenanceInfo := "Resource Name:" + resourceName + " Resource Kind:" + resourcePlural + "\n"
+	asOf := request.QueryParameter("asOf")
+	provenance.StoreLock.RLock()
+	defer provenance.StoreLock.RUnlock()
+	intendedProvObj := provenance.FindProvenanceObject(resourcePlural, namespace, resourceName)
+	if intendedProvObj == nil {
+		writeError(request, response, newNotFound(resourcePlural, namespace, resourceName), resourcePlural, resourceName)
+		return
+	}
+	if asOf == "" {
+		err := newBadRequest(resourcePlural, resourceName, "asOf", "asOf query parameter is missing")
+		writeError(request, response, err, resourcePlural, resourceName)
+		return
+	}
+	spec, err := intendedProvObj.ObjectFullHistory.VersionAsOf("asOf", asOf, time.Now())
+	if err != nil {
+		writeError(request, response, err, resourcePlural, resourceName)
+		return
+	}
+	text := provenanceInfo + "Timestamp: " + spec.Timestamp + "\n" + spec.String()
+	writeObject(request, response, newObjectVersion(intendedProvObj, asOf, spec), text)
 }
 
+// bisect answers a query given as fieldN, opN and valueN parameters, as
+// a JSON query tree in the query parameter, or as the body of a POST.
 func bisect(request *restful.Request, response *restful.Response) {
 	fmt.Println("Inside bisect")
-	resourceName := request.PathParameter("resource-id")
-	requestPath := request.Request.URL.String()
-	// assuming that the last slash is where the query starts.
-	strs := strings.Split(requestPath, "/")
+	resourcePlural, namespace, resourceName := objectOf(request)
 	// apis/kubeprovenance.cloudark.io/v1/namespaces/default/postgreses/client25/bisect?field1=username&field2=password&value1=pallavi&value2=pass123
-	// field1=username&field2=password&value1=pallavi&value2=pass123
-	args := strings.Split(strs[len(strs)-1], "?")[1] //get rid of "bisect?""
+	// the parameters are decoded, so values may hold escaped =, & and /
 	argMap := make(map[string]string)
-	argsArray := strings.Split(args, "&")
-	for _, val := range argsArray {
-		fieldToValue := strings.Split(val, "=")
-		argMap[fieldToValue[0]] = fieldToValue[1]
+	for key, values := range request.Request.URL.Query() {
+		//format, mode, explain and monotonic select the output and how it is
+		//found, query is a query tree
+		switch key {
+		case "format", "mode", "explain", "monotonic", "query":
+			continue
+		}
+		if len(values) == 0 {
+			continue
+		}
+		argMap[key] = values[0]
+	}
+	var queryTree *provenance.Query
+	var err error
+	switch {
+	case request.Request.Method == http.MethodPost:
+		var body []byte
+		body, err = ioutil.ReadAll(request.Request.Body)
+		if err == nil {
+			queryTree, err = provenance.ParseQuery(body)
+		}
+	case request.QueryParameter("query") != "":
+		queryTree, err = provenance.ParseQuery([]byte(request.QueryParameter("query")))
+	}
+	if err != nil {
+		writeError(request, response, err, resourcePlural, resourceName)
+		return
+	}
+	if queryTree != nil {
+		if len(argMap) > 0 {
+			message := "A query tree can not be combined with field, op and value parameters"
+			writeError(request, response, newBadRequest(resourcePlural, resourceName, "query", message), resourcePlural, resourceName)
+			return
+		}
+		argMap["query"] = queryTree.String()
+	}
+	explain, err := boolParameter(request, resourcePlural, resourceName, "explain")
+	if err != nil {
+		writeError(request, response, err, resourcePlural, resourceName)
+		return
+	}
+	if explain && request.QueryParameter("mode") == "transitions" {
+		message := "explain can not be combined with mode transitions, it already has the outcome of every version"
+		writeError(request, response, newBadRequest(resourcePlural, resourceName, "explain", message), resourcePlural, resourceName)
+		return
+	}
+	//a monotonic query is binary searched, only the first version is found
+	monotonic, err := boolParameter(request, resourcePlural, resourceName, "monotonic")
+	if err != nil {
+		writeError(request, response, err, resourcePlural, resourceName)
+		return
+	}
+	if monotonic && (explain || request.QueryParameter("mode") == "transitions") {
+		message := "monotonic can only be used to find the first version, not with explain or mode transitions"
+		writeError(request, response, newBadRequest(resourcePlural, resourceName, "monotonic", message), resourcePlural, resourceName)
+		return
 	}
-	// var provenanceInfo string
-	// provenanceInfo = "Resource Name:" + resourceName + " Resource Kind:" + resourceKind
-	// fmt.Println(provenanceInfo)
 
 	//Validate that there is ProvenanceHistory for the resource with name resourceName (PathParameter of the request)
-	intendedProvObj := provenance.FindProvenanceObjectByName(resourceName, provenance.AllProvenanceObjects)
+	provenance.StoreLock.RLock()
+	defer provenance.StoreLock.RUnlock()
+	intendedProvObj := provenance.FindProvenanceObject(resourcePlural, namespace, resourceName)
 	if intendedProvObj == nil {
-		s := fmt.Sprintf("Could not find any provenance history for resource name: %s", resourceName)
-		response.Write([]byte(s))
-	} else {
-		response.Write([]byte(intendedProvObj.ObjectFullHistory.Bisect(argMap)))
-		response.Write([]byte(string("\n")))
+		writeError(request, response, newNotFound(resourcePlural, namespace, resourceName), resourcePlural, resourceName)
+		return
+	}
+	lineage := intendedProvObj.ObjectFullHistory
+	switch request.QueryParameter("mode") {
+	case "", "first":
+	case "transitions":
+		var ranges []provenance.QueryRange
+		if queryTree != nil {
+			ranges, err = lineage.BisectQueryTransitions(queryTree)
+		} else {
+			ranges, err = lineage.BisectTransitions(argMap)
+		}
+		if err != nil {
+			writeError(request, response, err, resourcePlural, resourceName)
+			return
+		}
+		text := provenance.TransitionsString(ranges)
+		writeObject(request, response, newBisectTransitions(intendedProvObj, argMap, ranges), text)
+		return
+	default:
+		message := fmt.Sprintf("Unknown mode %s, want first or transitions", request.QueryParameter("mode"))
+		writeError(request, response, newBadRequest(resourcePlural, resourceName, "mode", message), resourcePlural, resourceName)
+		return
+	}
+	if explain {
+		var explanations []provenance.VersionExplanation
+		if queryTree != nil {
+			explanations, err = lineage.BisectQueryExplain(queryTree)
+		} else {
+			explanations, err = lineage.BisectExplain(argMap)
+		}
+		if err != nil {
+			writeError(request, response, err, resourcePlural, resourceName)
+			return
+		}
+		text := provenance.ExplainString(explanations)
+		writeObject(request, response, newBisectExplanation(intendedProvObj, argMap, explanations), text)
+		return
+	}
+	var spec provenance.Spec
+	var found bool
+	switch {
+	case queryTree != nil && monotonic:
+		spec, found, err = lineage.BisectQueryMonotonic(queryTree)
+	case queryTree != nil:
+		spec, found, err = lineage.BisectQuery(queryTree)
+	case monotonic:
+		spec, found, err = lineage.BisectVersionMonotonic(argMap)
+	default:
+		spec, found, err = lineage.BisectVersion(argMap)
+	}
+	if err != nil {
+		writeError(request, response, err, resourcePlural, resourceName)
+		return
+	}
+	text := provenance.BisectString(spec, found)
+	writeObject(request, response, newBisectResult(intendedProvObj, argMap, spec, found), text+"\n")
+}
+
+func getRollback(request *restful.Request, response *restful.Response) {
+	resourcePlural, namespace, resourceName := objectOf(request)
+	patchType := provenance.MergePatchType
+	if t := request.QueryParameter("type"); t != "" {
+		patchType = provenance.PatchType(t)
+	}
+	var fields []string
+	if f := request.QueryParameter("fields"); f != "" {
+		fields = strings.Split(f, ",")
+	}
+	provenance.StoreLock.RLock()
+	defer provenance.StoreLock.RUnlock()
+	intendedProvObj := provenance.FindProvenanceObject(resourcePlural, namespace, resourceName)
+	if intendedProvObj == nil {
+		writeError(request, response, newNotFound(resourcePlural, namespace, resourceName), resourcePlural, resourceName)
+		return
+	}
+	if request.QueryParameter("version") == "" {
+		err := newBadRequest(resourcePlural, resourceName, "version", "version query parameter is missing")
+		writeError(request, response, err, resourcePlural, resourceName)
+		return
+	}
+	version, err := versionParameter(request, resourcePlural, resourceName, "version")
+	if err != nil {
+		writeError(request, response, err, resourcePlural, resourceName)
+		return
+	}
+	patch, err := intendedProvObj.RollbackPatch(version, patchType, fields)
+	if err != nil {
+		writeError(request, response, err, resourcePlural, resourceName)
+		return
+	}
+	rollback := newRollbackPatch(intendedProvObj, version, patchType, fields, patch)
+	//the text is the patch alone, to pass it on to kubectl patch or apply
+	writeObject(request, response, rollback, string(rollback.Patch.Raw)+"\n")
+}
+
+func getFieldHistory(request *restful.Request, response *restful.Response) {
+	fmt.Println("Inside getFieldHistory")
+	resourcePlural, namespace, resourceName := objectOf(request)
+	field := request.QueryParameter("field")
+	if field == "" {
+		err := newBadRequest(resourcePlural, resourceName, "field", "field query parameter is missing")
+		writeError(request, response, err, resourcePlural, resourceName)
+		return
+	}
+	provenance.StoreLock.RLock()
+	defer provenance.StoreLock.RUnlock()
+	intendedProvObj := provenance.FindProvenanceObject(resourcePlural, namespace, resourceName)
+	if intendedProvObj == nil {
+		writeError(request, response, newNotFound(resourcePlural, namespace, resourceName), resourcePlural, resourceName)
+		return
+	}
+	values, err := intendedProvObj.ObjectFullHistory.FieldHistory(field)
+	if err != nil {
+		writeError(request, response, err, resourcePlural, resourceName)
+		return
+	}
+	text := provenance.FieldHistoryString(field, values)
+	writeObject(request, response, newFieldHistory(intendedProvObj, field, values), text)
+}
+
+func getFieldManagers(request *restful.Request, response *restful.Response) {
+	fmt.Println("Inside getFieldManagers")
+	resourcePlural, namespace, resourceName := objectOf(request)
+	//optional parameter
+	field := request.QueryParameter("field")
+	provenance.StoreLock.RLock()
+	defer provenance.StoreLock.RUnlock()
+	intendedProvObj := provenance.FindProvenanceObject(resourcePlural, namespace, resourceName)
+	if intendedProvObj == nil {
+		writeError(request, response, newNotFound(resourcePlural, namespace, resourceName), resourcePlural, resourceName)
+		return
+	}
+	text := intendedProvObj.ObjectFullHistory.FieldManagerHistory(field)
+	writeObject(request, response, newFieldManagerHistory(intendedProvObj, field), text)
+}
+
+func getStatusHistory(request *restful.Request, response *restful.Response) {
+	fmt.Println("Inside getStatusHistory")
+	resourcePlural, namespace, resourceName := objectOf(request)
+	provenance.StoreLock.RLock()
+	defer provenance.StoreLock.RUnlock()
+	intendedProvObj := provenance.FindProvenanceObject(resourcePlural, namespace, resourceName)
+	if intendedProvObj == nil {
+		writeError(request, response, newNotFound(resourcePlural, namespace, resourceName), resourcePlural, resourceName)
+		return
+	}
+	text := intendedProvObj.StatusHistory.SpecHistory()
+	writeObject(request, response, newSpecHistory(intendedProvObj, intendedProvObj.StatusHistory.SpecsInOrder()), text)
+}
+
+func getEvents(request *restful.Request, response *restful.Response) {
+	fmt.Println("Inside getEvents")
+	resourcePlural, namespace, resourceName := objectOf(request)
+	provenance.StoreLock.RLock()
+	defer provenance.StoreLock.RUnlock()
+	intendedProvObj := provenance.FindProvenanceObject(resourcePlural, namespace, resourceName)
+	if intendedProvObj == nil {
+		writeError(request, response, newNotFound(resourcePlural, namespace, resourceName), resourcePlural, resourceName)
+		return
+	}
+	writeObject(request, response, newSubresourceEventList(intendedProvObj), intendedProvObj.EventsString())
+}
+
+func getDeadLetters(request *restful.Request, response *restful.Response) {
+	fmt.Println("Inside getDeadLetters")
+	rejected, untracked := provenance.DeadLetterStores()
+	writeObject(request, response, newDeadLetterList(rejected, untracked), provenance.DeadLettersString(rejected, untracked))
+}
+
+func getDeadLetterCount(request *restful.Request, response *restful.Response) {
+	stored, total := provenance.DeadLetterCount()
+	s := fmt.Sprintf("Dead letters: %d stored, %d total\n", stored, total)
+	writeObject(request, response, &DeadLetterCount{Stored: stored, Total: total}, s)
+}
+
+func getDiscoveredKinds(request *restful.Request, response *restful.Response) {
+	kinds := provenance.DiscoveredKindList()
+	writeObject(request, response, newDiscoveredKindList(kinds), provenance.DiscoveredKindsString(kinds))
+}
+
+func reprocessDeadLetters(request *restful.Request, response *restful.Response) {
+	fmt.Println("Inside reprocessDeadLetters")
+	reprocessed := provenance.ReprocessDeadLetters()
+	s := fmt.Sprintf("Reprocessed %d dead letters\n", reprocessed)
+	writeObject(request, response, &ReprocessResult{Reprocessed: reprocessed}, s)
+}
+
+func receiveAuditEvents(request *restful.Request, response *restful.Response) {
+	body, err := ioutil.ReadAll(request.Request.Body)
+	if err != nil {
+		writeStatus(request, response, apierrors.NewBadRequest(err.Error()).ErrStatus)
+		return
 	}
+	if err := provenance.IngestEventList(body); err != nil {
+		writeStatus(request, response, apierrors.NewBadRequest(err.Error()).ErrStatus)
+		return
+	}
+	response.WriteHeader(http.StatusOK)
 }
 
 func getDiff(request *restful.Request, response *restful.Response) {
 	fmt.Println("Inside getDiff")
-	resourceName := request.PathParameter("resource-id")
-	requestPath := request.Request.URL.Path
-	resourcePathSlice := strings.Split(requestPath, "/")
-	resourceKind := resourcePathSlice[6] // Kind is 7th element in the slice
+	resourcePlural, namespace, resourceName := objectOf(request)
 
-	fmt.Printf("Resource Name:%s, Resource Kind:%s", resourceName, resourceKind)
+	fmt.Printf("Resource Name:%s, Resource Kind:%s", resourceName, resourcePlural)
 	start := request.QueryParameter("start")
 	end := request.QueryParameter("end")
 	field := request.QueryParameter("field")
-	intendedProvObj := provenance.FindProvenanceObjectByName(resourceName, provenance.AllProvenanceObjects)
+	provenance.StoreLock.RLock()
+	defer provenance.StoreLock.RUnlock()
+	intendedProvObj := provenance.FindProvenanceObject(resourcePlural, namespace, resourceName)
 	if intendedProvObj == nil {
-		s := fmt.Sprintf("Could not find any provenance history for resource name: %s", resourceName)
-		response.Write([]byte(s))
+		writeError(request, response, newNotFound(resourcePlural, namespace, resourceName), resourcePlural, resourceName)
 		return
 	}
 
-	var diffInfo string
-	if start == "" || end == "" {
-		fmt.Printf("Start:%s", start)
-		fmt.Printf("End:%s", end)
-		diffInfo = "Start and end query parameters are missing\n"
-	} else {
-		fmt.Printf("Start:%s", start)
-		fmt.Printf("End:%s", end)
-		startInt, err := strconv.Atoi(start)
-
-		if err != nil {
-			s := fmt.Sprintf("Could not parse start query parameter to int: %s", err.Error())
-			response.Write([]byte(s))
-			return
-		}
-		endInt, err := strconv.Atoi(end)
-		if err != nil {
-			s := fmt.Sprintf("Could not parse end query parameter to int: %s", err.Error())
-			response.Write([]byte(s))
-			return
+	fmt.Printf("Start:%s", start)
+	fmt.Printf("End:%s", end)
+	//either version numbers or times can be given for start and end
+	startTime := request.QueryParameter("startTime")
+	endTime := request.QueryParameter("endTime")
+	if (start == "" && startTime == "") || (end == "" && endTime == "") {
+		param := "start"
+		if start != "" || startTime != "" {
+			param = "end"
 		}
+		err := newBadRequest(resourcePlural, resourceName, param, "Start and end query parameters are missing")
+		writeError(request, response, err, resourcePlural, resourceName)
+		return
+	}
+	startInt, err := versionOrTimeParameter(request, intendedProvObj.ObjectFullHistory, resourcePlural, resourceName, "start", "startTime")
+	if err != nil {
+		writeError(request, response, err, resourcePlural, resourceName)
+		return
+	}
+	endInt, err := versionOrTimeParameter(request, intendedProvObj.ObjectFullHistory, resourcePlural, resourceName, "end", "endTime")
+	if err != nil {
+		writeError(request, response, err, resourcePlural, resourceName)
+		return
+	}
 
-		if field != "" {
-			fmt.Printf("Diff for Field requested. Field:%s", field)
-			diffInfo = intendedProvObj.ObjectFullHistory.FieldDiff(field, startInt, endInt)
-		} else {
-			fmt.Println("Diff for Full Spec requested.")
-			diffInfo = intendedProvObj.ObjectFullHistory.FullDiff(startInt, endInt)
-		}
+	var diffInfo string
+	if field != "" {
+		fmt.Printf("Diff for Field requested. Field:%s", field)
+		diffInfo, err = intendedProvObj.ObjectFullHistory.FieldDiff(field, startInt, endInt)
+	} else {
+		fmt.Println("Diff for Full Spec requested.")
+		diffInfo, err = intendedProvObj.ObjectFullHistory.FullDiff(startInt, endInt)
+	}
+	if err != nil {
+		writeError(request, response, err, resourcePlural, resourceName)
+		return
+	}
+	diff, err := newSpecDiff(intendedProvObj, field, startInt, endInt)
+	if err != nil {
+		writeError(request, response, err, resourcePlural, resourceName)
+		return
 	}
-	response.Write([]byte(diffInfo))
+	writeObject(request, response, diff, diffInfo)
 }
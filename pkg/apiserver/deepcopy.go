@@ -0,0 +1,464 @@
+package apiserver
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopy functions of the provenance response types, which are needed
+// to register them in the Scheme.
+
+func (in *ProvenanceVersionList) DeepCopyInto(out *ProvenanceVersionList) {
+	*out = *in
+	if in.Items != nil {
+		out.Items = make([]ProvenanceVersion, len(in.Items))
+		copy(out.Items, in.Items)
+	}
+}
+
+func (in *ProvenanceVersionList) DeepCopy() *ProvenanceVersionList {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvenanceVersionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *ProvenanceVersionList) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *SpecVersion) DeepCopyInto(out *SpecVersion) {
+	*out = *in
+	if in.Labels != nil {
+		out.Labels = make(map[string]string, len(in.Labels))
+		for key, val := range in.Labels {
+			out.Labels[key] = val
+		}
+	}
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+func (in *SpecHistory) DeepCopyInto(out *SpecHistory) {
+	*out = *in
+	if in.Items != nil {
+		out.Items = make([]SpecVersion, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+func (in *SpecHistory) DeepCopy() *SpecHistory {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecHistory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *SpecHistory) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *ProvenanceObjectVersion) DeepCopyInto(out *ProvenanceObjectVersion) {
+	*out = *in
+	in.Version.DeepCopyInto(&out.Version)
+}
+
+func (in *ProvenanceObjectVersion) DeepCopy() *ProvenanceObjectVersion {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvenanceObjectVersion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *ProvenanceObjectVersion) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *RollbackPatch) DeepCopyInto(out *RollbackPatch) {
+	*out = *in
+	if in.Fields != nil {
+		out.Fields = make([]string, len(in.Fields))
+		copy(out.Fields, in.Fields)
+	}
+	in.Patch.DeepCopyInto(&out.Patch)
+}
+
+func (in *RollbackPatch) DeepCopy() *RollbackPatch {
+	if in == nil {
+		return nil
+	}
+	out := new(RollbackPatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *RollbackPatch) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *AttributeDiff) DeepCopyInto(out *AttributeDiff) {
+	*out = *in
+	if in.From != nil {
+		out.From = in.From.DeepCopy()
+	}
+	if in.To != nil {
+		out.To = in.To.DeepCopy()
+	}
+}
+
+func (in *SpecDiff) DeepCopyInto(out *SpecDiff) {
+	*out = *in
+	if in.Items != nil {
+		out.Items = make([]AttributeDiff, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+func (in *SpecDiff) DeepCopy() *SpecDiff {
+	if in == nil {
+		return nil
+	}
+	out := new(SpecDiff)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *SpecDiff) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *BisectResult) DeepCopyInto(out *BisectResult) {
+	*out = *in
+	if in.Query != nil {
+		out.Query = make(map[string]string, len(in.Query))
+		for key, val := range in.Query {
+			out.Query[key] = val
+		}
+	}
+	if in.Version != nil {
+		out.Version = new(ProvenanceVersion)
+		*out.Version = *in.Version
+	}
+}
+
+func (in *BisectResult) DeepCopy() *BisectResult {
+	if in == nil {
+		return nil
+	}
+	out := new(BisectResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *BisectResult) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *QueryRange) DeepCopyInto(out *QueryRange) {
+	*out = *in
+	if in.End != nil {
+		out.End = new(ProvenanceVersion)
+		*out.End = *in.End
+	}
+}
+
+func (in *BisectTransitions) DeepCopyInto(out *BisectTransitions) {
+	*out = *in
+	if in.Query != nil {
+		out.Query = make(map[string]string, len(in.Query))
+		for key, val := range in.Query {
+			out.Query[key] = val
+		}
+	}
+	if in.Items != nil {
+		out.Items = make([]QueryRange, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+func (in *BisectTransitions) DeepCopy() *BisectTransitions {
+	if in == nil {
+		return nil
+	}
+	out := new(BisectTransitions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *BisectTransitions) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *VersionExplanation) DeepCopyInto(out *VersionExplanation) {
+	*out = *in
+	if in.Terms != nil {
+		out.Terms = make([]TermResult, len(in.Terms))
+		copy(out.Terms, in.Terms)
+	}
+}
+
+func (in *BisectExplanation) DeepCopyInto(out *BisectExplanation) {
+	*out = *in
+	if in.Query != nil {
+		out.Query = make(map[string]string, len(in.Query))
+		for key, val := range in.Query {
+			out.Query[key] = val
+		}
+	}
+	if in.Version != nil {
+		out.Version = new(ProvenanceVersion)
+		*out.Version = *in.Version
+	}
+	if in.Items != nil {
+		out.Items = make([]VersionExplanation, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+func (in *BisectExplanation) DeepCopy() *BisectExplanation {
+	if in == nil {
+		return nil
+	}
+	out := new(BisectExplanation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *BisectExplanation) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *FieldValue) DeepCopyInto(out *FieldValue) {
+	*out = *in
+	if in.Value != nil {
+		out.Value = in.Value.DeepCopy()
+	}
+}
+
+func (in *FieldHistory) DeepCopyInto(out *FieldHistory) {
+	*out = *in
+	if in.Items != nil {
+		out.Items = make([]FieldValue, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+func (in *FieldHistory) DeepCopy() *FieldHistory {
+	if in == nil {
+		return nil
+	}
+	out := new(FieldHistory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *FieldHistory) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *AttributeFieldManagers) DeepCopyInto(out *AttributeFieldManagers) {
+	*out = *in
+	if in.Changes != nil {
+		out.Changes = make([]FieldManagerChange, len(in.Changes))
+		copy(out.Changes, in.Changes)
+	}
+}
+
+func (in *FieldManagerHistory) DeepCopyInto(out *FieldManagerHistory) {
+	*out = *in
+	if in.Items != nil {
+		out.Items = make([]AttributeFieldManagers, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+func (in *FieldManagerHistory) DeepCopy() *FieldManagerHistory {
+	if in == nil {
+		return nil
+	}
+	out := new(FieldManagerHistory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *FieldManagerHistory) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *SubresourceEventList) DeepCopyInto(out *SubresourceEventList) {
+	*out = *in
+	if in.Items != nil {
+		out.Items = make([]SubresourceEvent, len(in.Items))
+		copy(out.Items, in.Items)
+	}
+}
+
+func (in *SubresourceEventList) DeepCopy() *SubresourceEventList {
+	if in == nil {
+		return nil
+	}
+	out := new(SubresourceEventList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *SubresourceEventList) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *ProvenanceObject) DeepCopyInto(out *ProvenanceObject) {
+	*out = *in
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+}
+
+func (in *ProvenanceObject) DeepCopy() *ProvenanceObject {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvenanceObject)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *ProvenanceObject) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *ProvenanceObjectList) DeepCopyInto(out *ProvenanceObjectList) {
+	*out = *in
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]ProvenanceObject, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+func (in *ProvenanceObjectList) DeepCopy() *ProvenanceObjectList {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvenanceObjectList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *ProvenanceObjectList) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *IngestionStatus) DeepCopyInto(out *IngestionStatus) {
+	*out = *in
+}
+
+func (in *IngestionStatus) DeepCopy() *IngestionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IngestionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *IngestionStatus) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *DeadLetterStore) DeepCopyInto(out *DeadLetterStore) {
+	*out = *in
+	if in.Items != nil {
+		out.Items = make([]DeadLetter, len(in.Items))
+		copy(out.Items, in.Items)
+	}
+}
+
+func (in *DeadLetterList) DeepCopyInto(out *DeadLetterList) {
+	*out = *in
+	in.DeadLetters.DeepCopyInto(&out.DeadLetters)
+	in.Untracked.DeepCopyInto(&out.Untracked)
+}
+
+func (in *DeadLetterList) DeepCopy() *DeadLetterList {
+	if in == nil {
+		return nil
+	}
+	out := new(DeadLetterList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *DeadLetterList) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *DeadLetterCount) DeepCopyInto(out *DeadLetterCount) {
+	*out = *in
+}
+
+func (in *DeadLetterCount) DeepCopy() *DeadLetterCount {
+	if in == nil {
+		return nil
+	}
+	out := new(DeadLetterCount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *DeadLetterCount) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *ReprocessResult) DeepCopyInto(out *ReprocessResult) {
+	*out = *in
+}
+
+func (in *ReprocessResult) DeepCopy() *ReprocessResult {
+	if in == nil {
+		return nil
+	}
+	out := new(ReprocessResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *ReprocessResult) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *DiscoveredKindList) DeepCopyInto(out *DiscoveredKindList) {
+	*out = *in
+	if in.Items != nil {
+		out.Items = make([]DiscoveredKind, len(in.Items))
+		copy(out.Items, in.Items)
+	}
+}
+
+func (in *DiscoveredKindList) DeepCopy() *DiscoveredKindList {
+	if in == nil {
+		return nil
+	}
+	out := new(DiscoveredKindList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *DiscoveredKindList) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
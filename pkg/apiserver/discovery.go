@@ -0,0 +1,68 @@
+package apiserver
+
+import (
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/endpoints/discovery"
+)
+
+// installGroupDiscovery lists the group version under /apis and
+// /apis/kubeprovenance.cloudark.io. InstallAPIGroup leaves out a version
+// without storage, and the provenance routes are not backed by storage.
+func installGroupDiscovery(provenanceServer *ProvenanceServer) {
+	version := metav1.GroupVersionForDiscovery{
+		GroupVersion: SchemeGroupVersion.String(),
+		Version:      GroupVersion,
+	}
+	apiGroup := metav1.APIGroup{
+		Name:             GroupName,
+		Versions:         []metav1.GroupVersionForDiscovery{version},
+		PreferredVersion: version,
+	}
+	provenanceServer.GenericAPIServer.DiscoveryGroupManager.AddGroup(apiGroup)
+	provenanceServer.GenericAPIServer.Handler.GoRestfulContainer.Add(discovery.NewAPIGroupHandler(Codecs, apiGroup).WebService())
+}
+
+// listAPIResources returns the resources of the discovery document of the
+// group version: a provenance resource for every tracked kind, e.g.
+// postgresprovenances, and a subresource for each of the routes of an
+// object. The list follows the tracked kinds, it is built for every request.
+func (s *ProvenanceServer) listAPIResources() []metav1.APIResource {
+	active := s.loadActiveKinds()
+	names := make([]string, 0, len(active))
+	for name, kind := range active {
+		// the plurals of the kinds are only answered for, not published
+		if name == provenanceResource(kind.Kind) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	resources := make([]metav1.APIResource, 0)
+	for _, name := range names {
+		kind := active[name]
+		// the objects can be listed, as a ProvenanceObjectList of
+		// ProvenanceObject, their lineages are read through the subresources
+		resources = append(resources, metav1.APIResource{
+			Name:       name,
+			Namespaced: !kind.ClusterScoped,
+			Kind:       "ProvenanceObject",
+			Verbs:      metav1.Verbs{"list"},
+			ShortNames: kind.ShortNames,
+		})
+		for _, route := range objectRoutes {
+			verbs := metav1.Verbs{"get"}
+			if route.post {
+				verbs = append(verbs, "create")
+			}
+			resources = append(resources, metav1.APIResource{
+				Name:       name + "/" + route.subresource,
+				Namespaced: !kind.ClusterScoped,
+				Kind:       route.kind,
+				Verbs:      verbs,
+			})
+		}
+	}
+	return resources
+}
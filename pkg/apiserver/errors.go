@@ -0,0 +1,162 @@
+package apiserver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emicklei/go-restful"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/cloud-ark/kubeprovenance/pkg/provenance"
+)
+
+// writeError answers with the status of err: 404 for a missing object,
+// version or attribute, 400 for a query that can not be parsed and 422 for
+// one that does not make sense. The body is a metav1.Status, or its message
+// with ?format=text. plural and name are the resource the query is about.
+func writeError(request *restful.Request, response *restful.Response, err error, plural, name string) {
+	writeStatus(request, response, statusForError(err, plural, name))
+}
+
+func writeStatus(request *restful.Request, response *restful.Response, status metav1.Status) {
+	mediaType, ok := negotiateMediaType(request)
+	if ok && mediaType == mimeText {
+		response.WriteErrorString(int(status.Code), status.Message+"\n")
+		return
+	}
+	if !ok {
+		mediaType = restful.MIME_JSON
+	}
+	for _, info := range Codecs.SupportedMediaTypes() {
+		if info.MediaType != mediaType {
+			continue
+		}
+		encoder := Codecs.EncoderForVersion(info.Serializer, SchemeGroupVersion)
+		response.AddHeader("Content-Type", info.MediaType)
+		response.WriteHeader(int(status.Code))
+		if err := encoder.Encode(&status, response); err != nil {
+			fmt.Printf("Could not encode status: %s\n", err)
+		}
+		return
+	}
+}
+
+func statusForError(err error, plural, name string) metav1.Status {
+	if statusErr, ok := err.(*apierrors.StatusError); ok {
+		return statusErr.ErrStatus
+	}
+	provErr, ok := err.(*provenance.Error)
+	if !ok {
+		return apierrors.NewInternalError(err).ErrStatus
+	}
+	resource := schema.GroupResource{Group: GroupName, Resource: plural}
+	switch provErr.Reason {
+	case provenance.ErrorReasonNotFound:
+		status := apierrors.NewNotFound(resource, name).ErrStatus
+		status.Message = provErr.Message
+		status.Details.Causes = []metav1.StatusCause{{
+			Type:    metav1.CauseTypeFieldValueNotFound,
+			Field:   provErr.Field,
+			Message: provErr.Message,
+		}}
+		return status
+	case provenance.ErrorReasonBadRequest:
+		return newBadRequest(plural, name, provErr.Field, provErr.Message).ErrStatus
+	case provenance.ErrorReasonInvalid:
+		kind := schema.GroupKind{Group: GroupName, Kind: kindOf(plural)}
+		errs := field.ErrorList{field.Invalid(field.NewPath(provErr.Field), provErr.Value, provErr.Message)}
+		return apierrors.NewInvalid(kind, name, errs).ErrStatus
+	}
+	return apierrors.NewInternalError(err).ErrStatus
+}
+
+// newNotFound is the error for an object without provenance history.
+// namespace is empty for cluster scoped kinds.
+func newNotFound(plural, namespace, name string) *apierrors.StatusError {
+	err := apierrors.NewNotFound(schema.GroupResource{Group: GroupName, Resource: plural}, name)
+	err.ErrStatus.Message = fmt.Sprintf("Could not find any provenance history for resource name: %s", name)
+	if namespace != "" {
+		err.ErrStatus.Message += " in namespace " + namespace
+	}
+	return err
+}
+
+// newBadRequest is the error for a query parameter that can not be parsed.
+func newBadRequest(plural, name, param, message string) *apierrors.StatusError {
+	err := apierrors.NewBadRequest(message)
+	err.ErrStatus.Details = &metav1.StatusDetails{
+		Group: GroupName,
+		Kind:  kindOf(plural),
+		Name:  name,
+		Causes: []metav1.StatusCause{{
+			Type:    metav1.CauseTypeFieldValueInvalid,
+			Field:   param,
+			Message: message,
+		}},
+	}
+	return err
+}
+
+// versionParameter parses the query parameter param as a version number.
+func versionParameter(request *restful.Request, plural, name, param string) (int, error) {
+	value := request.QueryParameter(param)
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		message := fmt.Sprintf("Could not parse %s query parameter to int: %s", param, err.Error())
+		return 0, newBadRequest(plural, name, param, message)
+	}
+	return version, nil
+}
+
+// boolParameter parses the query parameter param as a boolean, false if it
+// is not set.
+func boolParameter(request *restful.Request, plural, name, param string) (bool, error) {
+	value := request.QueryParameter(param)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		message := fmt.Sprintf("Could not parse %s query parameter to a boolean: %s", param, err.Error())
+		return false, newBadRequest(plural, name, param, message)
+	}
+	return b, nil
+}
+
+// The kind of plural for an Invalid status, or plural if it is not tracked
+// anymore.
+func kindOf(plural string) string {
+	for kind, p := range provenance.TrackedKinds() {
+		if strings.EqualFold(p, plural) {
+			return kind
+		}
+	}
+	return plural
+}
+
+// versionOrTimeParameter returns the version named by the query parameter
+// param, or the version that was in effect at the time timeParam names.
+// Returns 0 if neither is given.
+func versionOrTimeParameter(request *restful.Request, lineage provenance.ObjectLineage, plural, name, param, timeParam string) (int, error) {
+	value := request.QueryParameter(param)
+	timeValue := request.QueryParameter(timeParam)
+	switch {
+	case value != "" && timeValue != "":
+		message := fmt.Sprintf("Only one of the %s and %s query parameters can be given", param, timeParam)
+		return 0, newBadRequest(plural, name, timeParam, message)
+	case timeValue != "":
+		spec, err := lineage.VersionAsOf(timeParam, timeValue, time.Now())
+		if err != nil {
+			return 0, err
+		}
+		return spec.Version, nil
+	case value != "":
+		return versionParameter(request, plural, name, param)
+	}
+	return 0, nil
+}
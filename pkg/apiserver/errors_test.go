@@ -0,0 +1,104 @@
+package apiserver
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/emicklei/go-restful"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cloud-ark/kubeprovenance/pkg/provenance"
+)
+
+// Tests that the errors of the provenance package are answered with their
+// status, and that the kind of the details is that of the plural.
+func TestStatusForError(t *testing.T) {
+	provenance.KindPluralMap = map[string]string{"Postgres": "postgreses"}
+	_, notFound := provenance.ObjectLineage{}.FieldHistory("replicas")
+	_, badRequest := provenance.ObjectLineage{}.FieldHistory("users[username")
+	_, _, invalid := provenance.ObjectLineage{}.BisectVersion(map[string]string{"field1": "replicaz", "value1": "3"})
+
+	tests := []struct {
+		err    error
+		code   int32
+		reason metav1.StatusReason
+		kind   string
+	}{
+		{notFound, http.StatusNotFound, metav1.StatusReasonNotFound, "postgreses"},
+		{badRequest, http.StatusBadRequest, metav1.StatusReasonBadRequest, "Postgres"},
+		{invalid, http.StatusUnprocessableEntity, metav1.StatusReasonInvalid, "Postgres"},
+		{newNotFound("postgreses", "default", "client25"), http.StatusNotFound, metav1.StatusReasonNotFound, "postgreses"},
+		{newBadRequest("postgreses", "client25", "version", "bad version"), http.StatusBadRequest, metav1.StatusReasonBadRequest, "Postgres"},
+		{errors.New("broken"), http.StatusInternalServerError, metav1.StatusReasonInternalError, ""},
+	}
+	for _, test := range tests {
+		status := statusForError(test.err, "postgreses", "client25")
+		kind := ""
+		if status.Details != nil {
+			kind = status.Details.Kind
+		}
+		if status.Code != test.code || status.Reason != test.reason || kind != test.kind {
+			t.Errorf("Status for TestStatusForError() of %v was incorrect, got: %d %s %s, want: %d %s %s.\n", test.err, status.Code, status.Reason, kind, test.code, test.reason, test.kind)
+		}
+	}
+}
+
+// Tests that a status is written as text, JSON or YAML as the request
+// asks, and as JSON if the format can not be answered.
+func TestWriteStatus(t *testing.T) {
+	status := statusForError(newBadRequest("postgreses", "client25", "version", "bad version"), "postgreses", "client25")
+	tests := []struct {
+		target      string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"/?format=text", "", "", "bad version\n"},
+		{"/", "text/plain", "", "bad version\n"},
+		{"/", "", "application/json", `"reason":"BadRequest"`},
+		{"/?format=yaml", "", "application/yaml", "reason: BadRequest"},
+		{"/", "application/yaml", "application/yaml", "reason: BadRequest"},
+		{"/", "application/xml", "application/json", `"code":400`},
+		{"/?format=xml", "", "application/json", `"code":400`},
+	}
+	for _, test := range tests {
+		httpRequest := httptest.NewRequest("GET", test.target, nil)
+		if test.accept != "" {
+			httpRequest.Header.Set("Accept", test.accept)
+		}
+		recorder := httptest.NewRecorder()
+		writeStatus(restful.NewRequest(httpRequest), restful.NewResponse(recorder), status)
+		contentType := recorder.Header().Get("Content-Type")
+		if recorder.Code != http.StatusBadRequest || (test.contentType != "" && contentType != test.contentType) || !strings.Contains(recorder.Body.String(), test.body) {
+			t.Errorf("Response for TestWriteStatus() of %s %s was incorrect, got: %d %s %s, want: %d %s %s.\n", test.target, test.accept, recorder.Code, contentType, recorder.Body.String(), http.StatusBadRequest, test.contentType, test.body)
+		}
+	}
+}
+
+// Tests that a positive offset in asOf is read as such, although the + of
+// an unencoded query string is decoded to a space.
+func TestVersionOrTimeParameter(t *testing.T) {
+	lineage := provenance.ObjectLineage{
+		1: provenance.Spec{Version: 1, Timestamp: "2018-08-06 09:00:00"},
+		2: provenance.Spec{Version: 2, Timestamp: "2018-08-06 11:00:00"},
+	}
+	tests := []struct {
+		target  string
+		version int
+	}{
+		{"/?asOf=2018-08-06T12:00:00+02:00", 1},
+		{"/?asOf=2018-08-06T12:00:00%2B02:00", 1},
+		{"/?asOf=2018-08-06T12:00:00Z", 2},
+		{"/?version=2", 2},
+	}
+	for _, test := range tests {
+		request := restful.NewRequest(httptest.NewRequest("GET", test.target, nil))
+		version, err := versionOrTimeParameter(request, lineage, "postgreses", "client25", "version", "asOf")
+		if err != nil || version != test.version {
+			t.Errorf("Version for TestVersionOrTimeParameter() of %s was incorrect, got: %d %v, want: %d.\n", test.target, version, err, test.version)
+		}
+	}
+}
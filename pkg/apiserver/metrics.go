@@ -0,0 +1,45 @@
+package apiserver
+
+import (
+	"sync"
+	"time"
+
+	"github.com/emicklei/go-restful"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloud-ark/kubeprovenance/pkg/provenance"
+)
+
+var (
+	queryDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: "kubeprovenance",
+			Name:      "query_duration_seconds",
+			Help:      "Latency of the provenance queries, by endpoint.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"endpoint"},
+	)
+
+	registerMetrics sync.Once
+)
+
+// RegisterMetrics registers the query and ingestion metrics.
+func RegisterMetrics() {
+	registerMetrics.Do(func() {
+		prometheus.MustRegister(queryDuration)
+		provenance.RegisterMetrics()
+	})
+}
+
+// measureQuery observes the latency of a kind route under endpoint, the
+// fixed name of the route, e.g. versions. The path would make a label of
+// every namespace, object and version. It is the filter after
+// activeKindFilter, the kinds that are not tracked are not measured.
+func measureQuery(endpoint string) restful.FilterFunction {
+	return func(request *restful.Request, response *restful.Response, chain *restful.FilterChain) {
+		start := time.Now()
+		chain.ProcessFilter(request, response)
+		queryDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+	}
+}
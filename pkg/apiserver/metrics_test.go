@@ -0,0 +1,77 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emicklei/go-restful"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/cloud-ark/kubeprovenance/pkg/provenance"
+)
+
+// serveKinds answers request with the kind routes of a server that tracks
+// kinds, a map of kind to plural.
+func serveKinds(kinds map[string]string, request *http.Request) *httptest.ResponseRecorder {
+	provenance.KindPluralMap = kinds
+	return serveRoutes(request)
+}
+
+// serveRoutes answers request with the kind routes of a server that tracks
+// the kinds that are loaded.
+func serveRoutes(request *http.Request) *httptest.ResponseRecorder {
+	s := &ProvenanceServer{}
+	s.syncActiveKinds()
+	container := restful.NewContainer()
+	container.Router(restful.CurlyRouter{})
+	container.Add(s.kindWebService())
+	recorder := httptest.NewRecorder()
+	container.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// endpointCounts returns the number of requests measured per endpoint label.
+func endpointCounts(t *testing.T) map[string]uint64 {
+	metrics := make(chan prometheus.Metric, 100)
+	queryDuration.Collect(metrics)
+	close(metrics)
+	counts := make(map[string]uint64)
+	for metric := range metrics {
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil {
+			t.Fatalf("Could not read metric: %s", err)
+		}
+		for _, label := range m.GetLabel() {
+			counts[label.GetValue()] = m.GetHistogram().GetSampleCount()
+		}
+	}
+	return counts
+}
+
+// Tests that queries are measured by route, and that requests for kinds
+// that are not tracked are not measured.
+func TestMeasureQuery(t *testing.T) {
+	kinds := map[string]string{"Postgres": "postgreses"}
+	before := endpointCounts(t)
+	for _, target := range []string{
+		"/apis/kubeprovenance.cloudark.io/v1/namespaces/default/postgresprovenances/client25/versions",
+		"/apis/kubeprovenance.cloudark.io/v1/namespaces/other/postgreses/client26/versions",
+		"/apis/kubeprovenance.cloudark.io/v1/namespaces/default/postgreses/client25/versions/2",
+		"/apis/kubeprovenance.cloudark.io/v1/namespaces/default/postgreses",
+		"/apis/kubeprovenance.cloudark.io/v1/namespaces/default/probes/client25/versions",
+	} {
+		serveKinds(kinds, httptest.NewRequest("GET", target, nil))
+	}
+	after := endpointCounts(t)
+	want := map[string]uint64{"versions": 2, "manifest": 1, "list": 1}
+	for endpoint, n := range after {
+		if n-before[endpoint] != want[endpoint] {
+			t.Errorf("Count for TestMeasureQuery() of %s was incorrect, got: %d, want: %d.\n", endpoint, n-before[endpoint], want[endpoint])
+		}
+	}
+	if len(after) != len(want) {
+		t.Errorf("Endpoints for TestMeasureQuery() were incorrect, got: %v, want: %v.\n", after, want)
+	}
+}
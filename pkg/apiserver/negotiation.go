@@ -0,0 +1,115 @@
+package apiserver
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/emicklei/go-restful"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	mimeYAML = "application/yaml"
+	mimeText = "text/plain"
+)
+
+// writeObject writes obj in the format the client asked for: JSON or YAML
+// by the Accept header, or text, the output of earlier releases, with
+// ?format=text or Accept: text/plain. ?format=json and ?format=yaml
+// override the Accept header. Without either the response is JSON.
+func writeObject(request *restful.Request, response *restful.Response, obj runtime.Object, text string) {
+	mediaType, ok := negotiateMediaType(request)
+	if !ok {
+		writeNotAcceptable(request, response)
+		return
+	}
+	if mediaType == mimeText {
+		response.AddHeader("Content-Type", mimeText)
+		response.Write([]byte(text))
+		return
+	}
+	writeEncoded(response, mediaType, obj, true)
+}
+
+// writeManifest writes the object of a version in JSON or YAML, as it can be
+// applied again. It keeps its own apiVersion and kind, it is not converted
+// to the provenance group version. Text is answered with YAML.
+func writeManifest(request *restful.Request, response *restful.Response, manifest map[string]interface{}) {
+	mediaType, ok := negotiateMediaType(request)
+	if !ok {
+		writeNotAcceptable(request, response)
+		return
+	}
+	if mediaType == mimeText {
+		mediaType = mimeYAML
+	}
+	writeEncoded(response, mediaType, &unstructured.Unstructured{Object: manifest}, false)
+}
+
+func writeNotAcceptable(request *restful.Request, response *restful.Response) {
+	message := fmt.Sprintf("Only %s, %s and %s are supported", restful.MIME_JSON, mimeYAML, mimeText)
+	writeStatus(request, response, metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusNotAcceptable,
+		Reason:  metav1.StatusReasonNotAcceptable,
+		Message: message,
+	})
+}
+
+// writeEncoded writes obj with the serializer of mediaType, converted to
+// the provenance group version if versioned is set.
+func writeEncoded(response *restful.Response, mediaType string, obj runtime.Object, versioned bool) {
+	for _, info := range Codecs.SupportedMediaTypes() {
+		if info.MediaType != mediaType {
+			continue
+		}
+		var encoder runtime.Encoder = info.Serializer
+		if versioned {
+			encoder = Codecs.EncoderForVersion(info.Serializer, SchemeGroupVersion)
+		}
+		response.AddHeader("Content-Type", info.MediaType)
+		response.WriteHeader(http.StatusOK)
+		if err := encoder.Encode(obj, response); err != nil {
+			fmt.Printf("Could not encode %T: %s\n", obj, err)
+		}
+		return
+	}
+}
+
+func negotiateMediaType(request *restful.Request) (string, bool) {
+	switch request.QueryParameter("format") {
+	case "text":
+		return mimeText, true
+	case "json":
+		return restful.MIME_JSON, true
+	case "yaml":
+		return mimeYAML, true
+	case "":
+	default:
+		return "", false
+	}
+	accept := request.HeaderParameter("Accept")
+	if accept == "" {
+		return restful.MIME_JSON, true
+	}
+	// the first supported type wins, quality values are not weighed
+	for _, accepted := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case restful.MIME_JSON, "*/*", "application/*":
+			return restful.MIME_JSON, true
+		case mimeYAML, mimeText:
+			return mediaType, true
+		case "text/*":
+			return mimeText, true
+		}
+	}
+	return "", false
+}
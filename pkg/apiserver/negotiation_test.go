@@ -0,0 +1,99 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/emicklei/go-restful"
+)
+
+func TestNegotiateMediaType(t *testing.T) {
+	tests := []struct {
+		target    string
+		accept    string
+		mediaType string
+		ok        bool
+	}{
+		{"/", "", restful.MIME_JSON, true},
+		{"/?format=text", "application/json", mimeText, true},
+		{"/?format=json", "text/plain", restful.MIME_JSON, true},
+		{"/?format=yaml", "", mimeYAML, true},
+		{"/?format=xml", "", "", false},
+		{"/", "text/plain", mimeText, true},
+		{"/", "text/*", mimeText, true},
+		{"/", "application/yaml;q=0.9, application/json", mimeYAML, true},
+		{"/", "*/*", restful.MIME_JSON, true},
+		{"/", "application/xml", "", false},
+	}
+	for _, test := range tests {
+		httpRequest := httptest.NewRequest("GET", test.target, nil)
+		if test.accept != "" {
+			httpRequest.Header.Set("Accept", test.accept)
+		}
+		mediaType, ok := negotiateMediaType(restful.NewRequest(httpRequest))
+		if mediaType != test.mediaType || ok != test.ok {
+			t.Errorf("Media type for TestNegotiateMediaType() of %s %s was incorrect, got: %s %t, want: %s %t.\n", test.target, test.accept, mediaType, ok, test.mediaType, test.ok)
+		}
+	}
+}
+
+// Tests that an object is written in the negotiated format, and that a
+// format that can not be answered is a 406.
+func TestWriteObject(t *testing.T) {
+	tests := []struct {
+		target      string
+		accept      string
+		code        int
+		contentType string
+		body        string
+	}{
+		{"/?format=text", "", http.StatusOK, mimeText, "Version: 2"},
+		{"/", "", http.StatusOK, restful.MIME_JSON, `"kind":"BisectResult"`},
+		{"/", "application/yaml", http.StatusOK, mimeYAML, "kind: BisectResult"},
+		{"/", "application/xml", http.StatusNotAcceptable, restful.MIME_JSON, `"reason":"NotAcceptable"`},
+		{"/?format=xml", "", http.StatusNotAcceptable, restful.MIME_JSON, `"code":406`},
+	}
+	for _, test := range tests {
+		httpRequest := httptest.NewRequest("GET", test.target, nil)
+		if test.accept != "" {
+			httpRequest.Header.Set("Accept", test.accept)
+		}
+		recorder := httptest.NewRecorder()
+		writeObject(restful.NewRequest(httpRequest), restful.NewResponse(recorder), &BisectResult{Found: true}, "Version: 2")
+		contentType := recorder.Header().Get("Content-Type")
+		if recorder.Code != test.code || contentType != test.contentType || !strings.Contains(recorder.Body.String(), test.body) {
+			t.Errorf("Response for TestWriteObject() of %s %s was incorrect, got: %d %s %s, want: %d %s %s.\n", test.target, test.accept, recorder.Code, contentType, recorder.Body.String(), test.code, test.contentType, test.body)
+		}
+	}
+}
+
+// Tests that the diagnostics endpoints answer in the negotiated format.
+func TestDiagnosticsNegotiation(t *testing.T) {
+	tests := []struct {
+		target      string
+		contentType string
+		body        string
+	}{
+		{"/deadletters/count", restful.MIME_JSON, `"kind":"DeadLetterCount"`},
+		{"/deadletters/count?format=yaml", mimeYAML, "kind: DeadLetterCount"},
+		{"/deadletters/count?format=text", mimeText, "Dead letters: "},
+		{"/deadletters", restful.MIME_JSON, `"kind":"DeadLetterList"`},
+		{"/discoveredkinds", restful.MIME_JSON, `"kind":"DiscoveredKindList"`},
+	}
+	handlers := map[string]restful.RouteFunction{
+		"/deadletters/count": getDeadLetterCount,
+		"/deadletters":       getDeadLetters,
+		"/discoveredkinds":   getDiscoveredKinds,
+	}
+	for _, test := range tests {
+		httpRequest := httptest.NewRequest("GET", test.target, nil)
+		recorder := httptest.NewRecorder()
+		handlers[httpRequest.URL.Path](restful.NewRequest(httpRequest), restful.NewResponse(recorder))
+		contentType := recorder.Header().Get("Content-Type")
+		if recorder.Code != http.StatusOK || contentType != test.contentType || !strings.Contains(recorder.Body.String(), test.body) {
+			t.Errorf("Response for TestDiagnosticsNegotiation() of %s was incorrect, got: %d %s %s, want: %s %s.\n", test.target, recorder.Code, contentType, recorder.Body.String(), test.contentType, test.body)
+		}
+	}
+}
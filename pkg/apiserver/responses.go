@@ -0,0 +1,347 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/cloud-ark/kubeprovenance/pkg/provenance"
+)
+
+// Builders of the response types from the lineages of the provenance package
+
+func objectReference(p *provenance.ProvenanceOfObject) ProvenanceObjectReference {
+	return ProvenanceObjectReference{
+		Resource:  p.ResourcePlural,
+		Namespace: p.Namespace,
+		Name:      p.Name,
+	}
+}
+
+func versionOf(spec provenance.Spec) ProvenanceVersion {
+	return ProvenanceVersion{
+		Version:   spec.Version,
+		Timestamp: spec.Timestamp,
+		Actor:     spec.Actor,
+		Deleted:   spec.Deleted,
+		ChangeSet: spec.ChangeSet,
+	}
+}
+
+func rawExtension(data interface{}) runtime.RawExtension {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		raw = []byte("null")
+	}
+	return runtime.RawExtension{Raw: raw}
+}
+
+func newVersionList(p *provenance.ProvenanceOfObject) *ProvenanceVersionList {
+	list := &ProvenanceVersionList{
+		Object: objectReference(p),
+		Items:  make([]ProvenanceVersion, 0),
+	}
+	for _, spec := range p.ObjectFullHistory.SpecsInOrder() {
+		list.Items = append(list.Items, versionOf(spec))
+	}
+	return list
+}
+
+// newSpecHistory lists specs, the versions picked by the caller the same
+// way as for the text output.
+func newSpecHistory(p *provenance.ProvenanceOfObject, specs []provenance.Spec) *SpecHistory {
+	history := &SpecHistory{
+		Object: objectReference(p),
+		Items:  make([]SpecVersion, 0),
+	}
+	for _, spec := range specs {
+		history.Items = append(history.Items, specVersionOf(spec))
+	}
+	return history
+}
+
+func specVersionOf(spec provenance.Spec) SpecVersion {
+	return SpecVersion{
+		ProvenanceVersion: versionOf(spec),
+		Labels:            spec.Labels,
+		Spec:              rawExtension(spec.AttributeToData),
+	}
+}
+
+func newObjectVersion(p *provenance.ProvenanceOfObject, asOf string, spec provenance.Spec) *ProvenanceObjectVersion {
+	return &ProvenanceObjectVersion{
+		Object:  objectReference(p),
+		AsOf:    asOf,
+		Version: specVersionOf(spec),
+	}
+}
+
+func newSpecDiff(p *provenance.ProvenanceOfObject, field string, start, end int) (*SpecDiff, error) {
+	diffs, err := p.ObjectFullHistory.AttributeDiffs(field, start, end)
+	if err != nil {
+		return nil, err
+	}
+	diff := &SpecDiff{
+		Object: objectReference(p),
+		Start:  start,
+		End:    end,
+		Field:  field,
+		Items:  make([]AttributeDiff, 0),
+	}
+	for _, d := range diffs {
+		item := AttributeDiff{Attribute: d.Attribute}
+		if d.From != nil {
+			from := rawExtension(d.From)
+			item.From = &from
+		}
+		if d.To != nil {
+			to := rawExtension(d.To)
+			item.To = &to
+		}
+		diff.Items = append(diff.Items, item)
+	}
+	return diff, nil
+}
+
+func newBisectResult(p *provenance.ProvenanceOfObject, query map[string]string, spec provenance.Spec, found bool) *BisectResult {
+	result := &BisectResult{
+		Object: objectReference(p),
+		Query:  query,
+		Found:  found,
+	}
+	if found {
+		version := versionOf(spec)
+		result.Version = &version
+	}
+	return result
+}
+
+func newRollbackPatch(p *provenance.ProvenanceOfObject, version int, patchType provenance.PatchType, fields []string, patch map[string]interface{}) *RollbackPatch {
+	from := 0
+	if specs := p.ObjectFullHistory.SpecsInOrder(); len(specs) > 0 {
+		from = specs[len(specs)-1].Version
+	}
+	return &RollbackPatch{
+		Object: objectReference(p),
+		From:   from,
+		To:     version,
+		Type:   string(patchType),
+		Fields: fields,
+		Patch:  rawExtension(patch),
+	}
+}
+
+func newBisectTransitions(p *provenance.ProvenanceOfObject, query map[string]string, ranges []provenance.QueryRange) *BisectTransitions {
+	transitions := &BisectTransitions{
+		Object: objectReference(p),
+		Query:  query,
+		Items:  make([]QueryRange, 0),
+	}
+	for _, r := range ranges {
+		item := QueryRange{Start: versionOf(r.Start), Last: r.Last}
+		if r.End != nil {
+			end := versionOf(*r.End)
+			item.End = &end
+		}
+		transitions.Items = append(transitions.Items, item)
+	}
+	return transitions
+}
+
+func newBisectExplanation(p *provenance.ProvenanceOfObject, query map[string]string, explanations []provenance.VersionExplanation) *BisectExplanation {
+	explanation := &BisectExplanation{
+		Object: objectReference(p),
+		Query:  query,
+		Items:  make([]VersionExplanation, 0),
+	}
+	for _, e := range explanations {
+		version := versionOf(e.Spec)
+		if e.Matched && !explanation.Found {
+			explanation.Found = true
+			explanation.Version = &version
+		}
+		item := VersionExplanation{Version: version, Matched: e.Matched, Terms: make([]TermResult, 0)}
+		for _, term := range e.Terms {
+			item.Terms = append(item.Terms, TermResult{
+				Param:   term.Param,
+				Term:    term.Term,
+				Passed:  term.Passed,
+				Element: term.Element,
+			})
+		}
+		explanation.Items = append(explanation.Items, item)
+	}
+	return explanation
+}
+
+func newFieldHistory(p *provenance.ProvenanceOfObject, field string, values []provenance.FieldValue) *FieldHistory {
+	history := &FieldHistory{
+		Object: objectReference(p),
+		Field:  field,
+		Items:  make([]FieldValue, 0),
+	}
+	for _, v := range values {
+		item := FieldValue{Start: versionOf(v.Start), Last: v.Last, Set: v.Set}
+		if v.Set {
+			value := rawExtension(v.Value)
+			item.Value = &value
+		}
+		history.Items = append(history.Items, item)
+	}
+	return history
+}
+
+// newFieldManagerHistory lists the field managers per field path, only of
+// the paths within field if it is not empty.
+func newFieldManagerHistory(p *provenance.ProvenanceOfObject, field string) *FieldManagerHistory {
+	changes := make(map[string][]FieldManagerChange)
+	for _, spec := range p.ObjectFullHistory.SpecsInOrder() {
+		for attribute, manager := range spec.FieldManagers {
+			if field != "" && !provenance.UnderField(attribute, field) {
+				continue
+			}
+			changes[attribute] = append(changes[attribute], FieldManagerChange{
+				Version:   spec.Version,
+				Timestamp: spec.Timestamp,
+				Manager:   manager,
+				Actor:     spec.Actor,
+			})
+		}
+	}
+	var attributes []string
+	for attribute := range changes {
+		attributes = append(attributes, attribute)
+	}
+	sort.Strings(attributes)
+	history := &FieldManagerHistory{
+		Object: objectReference(p),
+		Items:  make([]AttributeFieldManagers, 0),
+	}
+	for _, attribute := range attributes {
+		history.Items = append(history.Items, AttributeFieldManagers{Attribute: attribute, Changes: changes[attribute]})
+	}
+	return history
+}
+
+func newSubresourceEventList(p *provenance.ProvenanceOfObject) *SubresourceEventList {
+	list := &SubresourceEventList{
+		Object: objectReference(p),
+		Items:  make([]SubresourceEvent, 0),
+	}
+	for _, e := range p.Events {
+		list.Items = append(list.Items, SubresourceEvent{
+			Timestamp:   e.Timestamp,
+			Verb:        e.Verb,
+			Subresource: e.Subresource,
+			Actor:       e.Actor,
+			Annotation:  e.Annotation,
+		})
+	}
+	return list
+}
+
+// newObjectList lists the objects of a kind. writeObject sets the apiVersion
+// and kind of the list, the items carry their own as in any list.
+func newObjectList(objects []*provenance.ProvenanceOfObject) *ProvenanceObjectList {
+	list := &ProvenanceObjectList{
+		Items: make([]ProvenanceObject, 0),
+	}
+	for _, p := range objects {
+		summary := p.Summary()
+		list.Items = append(list.Items, ProvenanceObject{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: SchemeGroupVersion.String(),
+				Kind:       "ProvenanceObject",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      p.Name,
+				Namespace: p.Namespace,
+				Labels:    summary.Labels,
+			},
+			Object:      objectReference(p),
+			Versions:    summary.Versions,
+			FirstChange: summary.FirstChange,
+			LastChange:  summary.LastChange,
+			LastActor:   summary.LastActor,
+			Deleted:     summary.Deleted,
+		})
+	}
+	return list
+}
+
+func newIngestionStatus(s provenance.CollectorStatus) *IngestionStatus {
+	return &IngestionStatus{
+		Running:           s.Running,
+		Started:           rfc3339(s.Started),
+		Panicked:          s.Panicked,
+		LogPath:           s.LogPath,
+		Offset:            s.Offset,
+		FileSize:          s.FileSize,
+		Lag:               s.Lag(),
+		LastRead:          rfc3339(s.LastRead),
+		LastSuccess:       rfc3339(s.LastSuccess),
+		Done:              s.Done,
+		Errors:            s.Errors,
+		ConsecutiveErrors: s.ConsecutiveErrors,
+		LastError:         s.LastError,
+	}
+}
+
+// rfc3339 formats t in UTC, empty for the zero time.
+func rfc3339(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func newDeadLetterList(rejected, untracked provenance.DeadLetterSnapshot) *DeadLetterList {
+	return &DeadLetterList{
+		DeadLetters: newDeadLetterStore(rejected),
+		Untracked:   newDeadLetterStore(untracked),
+	}
+}
+
+func newDeadLetterStore(s provenance.DeadLetterSnapshot) DeadLetterStore {
+	store := DeadLetterStore{
+		Stored:  len(s.Letters),
+		Total:   s.Total,
+		Dropped: s.Dropped,
+		Items:   make([]DeadLetter, 0, len(s.Letters)),
+	}
+	for _, letter := range s.Letters {
+		store.Items = append(store.Items, DeadLetter{
+			Source:      letter.Source,
+			Offset:      letter.Offset,
+			Reason:      letter.Reason,
+			Partial:     letter.Partial,
+			Untracked:   letter.Untracked,
+			Timestamp:   letter.Timestamp,
+			Verb:        letter.Verb,
+			Resource:    letter.Resource,
+			Namespace:   letter.Namespace,
+			Name:        letter.Name,
+			Subresource: letter.Subresource,
+		})
+	}
+	return store
+}
+
+func newDiscoveredKindList(kinds []provenance.DiscoveredKind) *DiscoveredKindList {
+	list := &DiscoveredKindList{Items: make([]DiscoveredKind, 0, len(kinds))}
+	for _, k := range kinds {
+		list.Items = append(list.Items, DiscoveredKind{
+			Resource:      k.Resource,
+			Kind:          k.Kind,
+			Plural:        k.Plural,
+			Group:         k.Group,
+			Endpoint:      k.Endpoint,
+			ClusterScoped: k.ClusterScoped,
+			Tracked:       k.Tracked,
+		})
+	}
+	return list
+}
@@ -0,0 +1,87 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/cloud-ark/kubeprovenance/pkg/provenance"
+)
+
+// Tests that the list of a provenance resource decodes to the kind that
+// discovery publishes for it, with the metadata of its objects.
+func TestObjectListRoundTrip(t *testing.T) {
+	provObj := provenance.NewProvenanceOfObject()
+	provObj.ResourcePlural = "postgreses"
+	provObj.Namespace = "default"
+	provObj.Name = "client25"
+	provObj.ObjectFullHistory[1] = provenance.Spec{Version: 1, Timestamp: "2018-08-05 00:16:20", Labels: map[string]string{"tier": "front"}}
+
+	bytes, err := runtime.Encode(Codecs.LegacyCodec(SchemeGroupVersion), newObjectList([]*provenance.ProvenanceOfObject{provObj}))
+	if err != nil {
+		t.Fatalf("Could not encode the list: %s", err)
+	}
+	obj, err := runtime.Decode(Codecs.UniversalDecoder(SchemeGroupVersion), bytes)
+	list, ok := obj.(*ProvenanceObjectList)
+	if err != nil || !ok || len(list.Items) != 1 {
+		t.Fatalf("List for TestObjectListRoundTrip() was incorrect, got: %v %v, want: a ProvenanceObjectList of 1 item.\n", obj, err)
+	}
+	if !strings.Contains(string(bytes), `"metadata":{}`) {
+		t.Errorf("List for TestObjectListRoundTrip() has no list metadata, got: %s.\n", bytes)
+	}
+	item := list.Items[0]
+	if item.APIVersion != "kubeprovenance.cloudark.io/v1" || item.Kind != "ProvenanceObject" {
+		t.Errorf("Type of the item for TestObjectListRoundTrip() was incorrect, got: %s %s, want: kubeprovenance.cloudark.io/v1 ProvenanceObject.\n", item.APIVersion, item.Kind)
+	}
+	if item.Name != "client25" || item.Namespace != "default" || item.Labels["tier"] != "front" || item.Versions != 1 {
+		t.Errorf("Item for TestObjectListRoundTrip() was incorrect, got: %v.\n", item)
+	}
+	if kinds, _, err := Scheme.ObjectKinds(&ProvenanceObject{}); err != nil || kinds[0].Kind != "ProvenanceObject" {
+		t.Errorf("Kind for TestObjectListRoundTrip() was incorrect, got: %v %v, want: ProvenanceObject.\n", kinds, err)
+	}
+}
+
+// Tests that the spec history of an interval has the versions of the text
+// output, also when the interval ends at version 0.
+func TestSpecHistoryInterval(t *testing.T) {
+	provObj := provenance.NewProvenanceOfObject()
+	provObj.ResourcePlural = "postgreses"
+	provObj.Namespace = "default"
+	provObj.Name = "client27"
+	for version, timestamp := range []string{"2018-08-05 00:16:20", "2018-08-05 00:17:20", "2018-08-05 00:18:20"} {
+		provObj.ObjectFullHistory[version+1] = provenance.Spec{Version: version + 1, Timestamp: timestamp}
+	}
+	old := provenance.AllProvenanceObjects
+	provenance.AllProvenanceObjects = append(old, provObj)
+	defer func() { provenance.AllProvenanceObjects = old }()
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"start=2&end=3", []int{2, 3}},
+		{"start=2&end=2", []int{2}},
+		{"start=0&end=0", []int{}},
+		{"", []int{1, 2, 3}},
+	}
+	for _, test := range tests {
+		target := "/apis/kubeprovenance.cloudark.io/v1/namespaces/default/postgreses/client27/spechistory?" + test.query
+		recorder := serveKinds(map[string]string{"Postgres": "postgreses"}, httptest.NewRequest("GET", target, nil))
+		var history SpecHistory
+		if err := json.Unmarshal(recorder.Body.Bytes(), &history); err != nil {
+			t.Errorf("Spec history for TestSpecHistoryInterval() %s could not be parsed, got: %s %v.\n", test.query, recorder.Body.String(), err)
+			continue
+		}
+		got := make([]int, 0)
+		for _, item := range history.Items {
+			got = append(got, item.Version)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Versions of the spec history for TestSpecHistoryInterval() %s were incorrect, got: %v, want: %v.\n", test.query, got, test.want)
+		}
+	}
+}
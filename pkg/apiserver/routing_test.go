@@ -0,0 +1,63 @@
+package apiserver
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cloud-ark/kubeprovenance/pkg/provenance"
+)
+
+// loadCompositions loads the kind compositions in content.
+func loadCompositions(t *testing.T, content string) {
+	file, err := ioutil.TempFile("", "compositions")
+	if err != nil {
+		t.Fatalf("Could not create the kind compositions file: %s", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatalf("Could not write the kind compositions file: %s", err)
+	}
+	file.Close()
+	if err := provenance.ReadKindCompositionFile(file.Name()); err != nil {
+		t.Fatalf("ReadKindCompositionFile() returned an error: %s", err)
+	}
+}
+
+// Tests that plurals that collide with the namespace and subresource path
+// elements are only served under the provenance resource of the kind.
+func TestAmbiguousPlurals(t *testing.T) {
+	loadCompositions(t, `
+- kind: Postgres
+  plural: postgreses
+  endpoint: apis/postgrescontroller.kubeplus/v1
+- kind: Namespace
+  plural: namespaces
+  endpoint: api/v1
+  scope: Cluster
+- kind: Event
+  plural: events
+  endpoint: api/v1
+`)
+	defer loadCompositions(t, "[]")
+	tests := []struct {
+		target  string
+		code    int
+		message string
+	}{
+		{"/namespaces/default/postgreses", 200, ""},
+		{"/namespaces/default/eventprovenances", 200, ""},
+		{"/namespaceprovenances/default/versions", 404, "Could not find any provenance history"},
+		{"/namespaces/default/events", 404, "not tracked"},
+		{"/namespaces/default/versions", 404, "not tracked"},
+	}
+	for _, test := range tests {
+		recorder := serveRoutes(httptest.NewRequest("GET", "/apis/kubeprovenance.cloudark.io/v1"+test.target, nil))
+		if recorder.Code != test.code || !strings.Contains(recorder.Body.String(), test.message) {
+			t.Errorf("Response to %s for TestAmbiguousPlurals() was incorrect, got: %d %s, want: %d %s.\n",
+				test.target, recorder.Code, recorder.Body.String(), test.code, test.message)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package apiserver
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// The object a provenance response is about
+type ProvenanceObjectReference struct {
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// One version of the lineage of an object
+type ProvenanceVersion struct {
+	Version   int    `json:"version"`
+	Timestamp string `json:"timestamp"`
+	// user that made the request
+	Actor   string `json:"actor,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"`
+	// auditID of a request that changed several objects at once
+	ChangeSet string `json:"changeSet,omitempty"`
+}
+
+// ProvenanceVersionList is the response of the versions endpoint.
+type ProvenanceVersionList struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Object ProvenanceObjectReference `json:"object"`
+	Items  []ProvenanceVersion       `json:"items"`
+}
+
+// A version together with the spec, or status, it recorded
+type SpecVersion struct {
+	ProvenanceVersion `json:",inline"`
+
+	Labels map[string]string    `json:"labels,omitempty"`
+	Spec   runtime.RawExtension `json:"spec"`
+}
+
+// SpecHistory is the response of the spechistory and statushistory endpoints.
+type SpecHistory struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Object ProvenanceObjectReference `json:"object"`
+	Items  []SpecVersion             `json:"items"`
+}
+
+// ProvenanceObjectVersion is the response of the version endpoint, the
+// version that was in effect at an instant.
+type ProvenanceObjectVersion struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Object ProvenanceObjectReference `json:"object"`
+	// the instant that was asked for, as given
+	AsOf    string      `json:"asOf"`
+	Version SpecVersion `json:"version"`
+}
+
+// The values of an attribute that differs between two versions. A value is
+// left out when the attribute does not exist in that version.
+type AttributeDiff struct {
+	Attribute string                `json:"attribute"`
+	From      *runtime.RawExtension `json:"from,omitempty"`
+	To        *runtime.RawExtension `json:"to,omitempty"`
+}
+
+// SpecDiff is the response of the diff endpoint.
+type SpecDiff struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Object ProvenanceObjectReference `json:"object"`
+	Start  int                       `json:"start"`
+	End    int                       `json:"end"`
+	// the attribute that was compared, all attributes if empty
+	Field string          `json:"field,omitempty"`
+	Items []AttributeDiff `json:"items"`
+}
+
+// BisectResult is the response of the bisect endpoint.
+type BisectResult struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Object ProvenanceObjectReference `json:"object"`
+	Query  map[string]string         `json:"query"`
+	Found  bool                      `json:"found"`
+	// the first version that satisfies the query
+	Version *ProvenanceVersion `json:"version,omitempty"`
+}
+
+// A run of versions over which a bisect query held
+type QueryRange struct {
+	// the version that made the query hold
+	Start ProvenanceVersion `json:"start"`
+	// the last version of the run
+	Last int `json:"last"`
+	// the version that made the query stop holding, left out if it still
+	// holds at the latest version
+	End *ProvenanceVersion `json:"end,omitempty"`
+}
+
+// BisectTransitions is the response of the bisect endpoint with
+// mode=transitions.
+type BisectTransitions struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Object ProvenanceObjectReference `json:"object"`
+	Query  map[string]string         `json:"query"`
+	Items  []QueryRange              `json:"items"`
+}
+
+// The outcome of one term of a bisect query for a version
+type TermResult struct {
+	// the query parameter of the term, e.g. field2
+	Param  string `json:"param"`
+	Term   string `json:"term"`
+	Passed bool   `json:"passed"`
+	// the list element the term was checked against, e.g. users[1]
+	Element string `json:"element,omitempty"`
+}
+
+// Why a version did or did not satisfy a bisect query
+type VersionExplanation struct {
+	Version ProvenanceVersion `json:"version"`
+	Matched bool              `json:"matched"`
+	Terms   []TermResult      `json:"terms"`
+}
+
+// BisectExplanation is the response of the bisect endpoint with
+// explain=true.
+type BisectExplanation struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Object ProvenanceObjectReference `json:"object"`
+	Query  map[string]string         `json:"query"`
+	Found  bool                      `json:"found"`
+	// the first version that satisfies the query
+	Version *ProvenanceVersion   `json:"version,omitempty"`
+	Items   []VersionExplanation `json:"items"`
+}
+
+// The value of a field over a run of versions
+type FieldValue struct {
+	// the version that set the value
+	Start ProvenanceVersion `json:"start"`
+	// the last version of the run
+	Last int `json:"last"`
+	// false if the field was not set, or the object was deleted
+	Set   bool                  `json:"set"`
+	Value *runtime.RawExtension `json:"value,omitempty"`
+}
+
+// FieldHistory is the response of the fieldhistory endpoint.
+type FieldHistory struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Object ProvenanceObjectReference `json:"object"`
+	Field  string                    `json:"field"`
+	Items  []FieldValue              `json:"items"`
+}
+
+// RollbackPatch is the response of the rollback endpoint, the patch that
+// moves the object from its latest version back to an earlier one.
+type RollbackPatch struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Object ProvenanceObjectReference `json:"object"`
+	// the latest version, that the patch applies to, and the version it restores
+	From int `json:"from"`
+	To   int `json:"to"`
+	// merge, strategic or apply
+	Type string `json:"type"`
+	// the fields of the spec the patch touches, the whole spec and the labels if empty
+	Fields []string             `json:"fields,omitempty"`
+	Patch  runtime.RawExtension `json:"patch"`
+}
+
+// A change of an attribute and the field manager that made it
+type FieldManagerChange struct {
+	Version   int    `json:"version"`
+	Timestamp string `json:"timestamp"`
+	Manager   string `json:"manager"`
+	Actor     string `json:"actor,omitempty"`
+}
+
+type AttributeFieldManagers struct {
+	// the field path, e.g. users[username=daniel].password
+	Attribute string               `json:"attribute"`
+	Changes   []FieldManagerChange `json:"changes"`
+}
+
+// FieldManagerHistory is the response of the fieldmanagers endpoint.
+type FieldManagerHistory struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Object ProvenanceObjectReference `json:"object"`
+	Items  []AttributeFieldManagers  `json:"items"`
+}
+
+// A write to a subresource that did not create a version
+type SubresourceEvent struct {
+	Timestamp   string `json:"timestamp"`
+	Verb        string `json:"verb"`
+	Subresource string `json:"subresource"`
+	Actor       string `json:"actor,omitempty"`
+	Annotation  string `json:"annotation,omitempty"`
+}
+
+// SubresourceEventList is the response of the events endpoint.
+type SubresourceEventList struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Object ProvenanceObjectReference `json:"object"`
+	Items  []SubresourceEvent        `json:"items"`
+}
+
+// ProvenanceObject is the lineage of an object in short, the kind of the
+// provenance resources, e.g. postgresprovenances. Its metadata has the name
+// and namespace of the object, and the labels of its last version that was
+// not a deletion.
+type ProvenanceObject struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Object      ProvenanceObjectReference `json:"object"`
+	Versions    int                       `json:"versions"`
+	FirstChange string                    `json:"firstChange,omitempty"`
+	LastChange  string                    `json:"lastChange,omitempty"`
+	LastActor   string                    `json:"lastActor,omitempty"`
+	Deleted     bool                      `json:"deleted,omitempty"`
+}
+
+// ProvenanceObjectList is the response of the list endpoint of a kind.
+type ProvenanceObjectList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ProvenanceObject `json:"items"`
+}
+
+// IngestionStatus is the response of the ingestion endpoint, what the
+// collector of the audit log has been doing. Times are RFC3339, empty if
+// it did not happen yet.
+type IngestionStatus struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Running  bool   `json:"running"`
+	Started  string `json:"started,omitempty"`
+	Panicked string `json:"panicked,omitempty"`
+	LogPath  string `json:"logPath"`
+	Offset   int64  `json:"offset"`
+	// -1 if the log could not be read
+	FileSize int64 `json:"fileSize"`
+	// bytes of the log that are not read yet
+	Lag               int64  `json:"lag"`
+	LastRead          string `json:"lastRead,omitempty"`
+	LastSuccess       string `json:"lastSuccess,omitempty"`
+	Done              bool   `json:"done,omitempty"`
+	Errors            int    `json:"errors"`
+	ConsecutiveErrors int    `json:"consecutiveErrors"`
+	LastError         string `json:"lastError,omitempty"`
+}
+
+// An audit event that was rejected or only partially parsed, it does not
+// hold the event
+type DeadLetter struct {
+	Source      string `json:"source"`
+	Offset      int64  `json:"offset"`
+	Reason      string `json:"reason"`
+	Partial     bool   `json:"partial,omitempty"`
+	Untracked   bool   `json:"untracked,omitempty"`
+	Timestamp   string `json:"timestamp"`
+	Verb        string `json:"verb,omitempty"`
+	Resource    string `json:"resource,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Subresource string `json:"subresource,omitempty"`
+}
+
+type DeadLetterStore struct {
+	Stored  int          `json:"stored"`
+	Total   int          `json:"total"`
+	Dropped int          `json:"dropped"`
+	Items   []DeadLetter `json:"items"`
+}
+
+// DeadLetterList is the response of the deadletters endpoint.
+type DeadLetterList struct {
+	metav1.TypeMeta `json:",inline"`
+
+	DeadLetters DeadLetterStore `json:"deadLetters"`
+	// writes to resources that were not tracked when they were read
+	Untracked DeadLetterStore `json:"untracked"`
+}
+
+// DeadLetterCount is the response of the deadletters/count endpoint.
+type DeadLetterCount struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Stored int `json:"stored"`
+	Total  int `json:"total"`
+}
+
+// ReprocessResult is the response of the deadletters/reprocess endpoint.
+type ReprocessResult struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Reprocessed int `json:"reprocessed"`
+}
+
+// A kind that was learned from the audit stream
+type DiscoveredKind struct {
+	// plural.group of the resource
+	Resource      string `json:"resource"`
+	Kind          string `json:"kind"`
+	Plural        string `json:"plural"`
+	Group         string `json:"group,omitempty"`
+	Endpoint      string `json:"endpoint"`
+	ClusterScoped bool   `json:"clusterScoped,omitempty"`
+	// false if the kind or its plural was taken by another kind
+	Tracked bool `json:"tracked"`
+}
+
+// DiscoveredKindList is the response of the discoveredkinds endpoint.
+type DiscoveredKindList struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Items []DiscoveredKind `json:"items"`
+}
@@ -3,9 +3,14 @@ package serverstrings
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/cloud-ark/kubeprovenance/pkg/apiserver"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
@@ -17,20 +22,82 @@ const defaultEtcdPathPrefix = "/registry/kubeprovenance.clouarark.io"
 
 type ProvenanceServerOptions struct {
 	RecommendedOptions *genericoptions.RecommendedOptions
-	StdOut             io.Writer
-	StdErr             io.Writer
+
+	Source              string
+	AuditLogPath        string
+	SampleLogPath       string
+	KindCompositionFile string
+	PollInterval        time.Duration
+	StallThreshold      time.Duration
+	DiscoverKinds       bool
+	IncludeKinds        []string
+	ExcludeKinds        []string
+	RedactionSalt       string
+	RedactionSaltFile   string
+
+	StdOut io.Writer
+	StdErr io.Writer
 }
 
 func NewProvenanceServerOptions(out, errOut io.Writer) *ProvenanceServerOptions {
 	o := &ProvenanceServerOptions{
 		RecommendedOptions: genericoptions.NewRecommendedOptions(defaultEtcdPathPrefix,
 			apiserver.Codecs.LegacyCodec(apiserver.SchemeGroupVersion)),
-		StdOut: out,
-		StdErr: errOut,
+		Source:              apiserver.SourceFile,
+		AuditLogPath:        "/tmp/kube-apiserver-audit.log",
+		SampleLogPath:       "/tmp/minikube-sample-audit.log",
+		KindCompositionFile: "/etc/kubeprovenance/kind_compositions.yaml",
+		PollInterval:        5 * time.Second,
+		StallThreshold:      time.Minute,
+		StdOut:              out,
+		StdErr:              errOut,
 	}
 	return o
 }
 
+func (o *ProvenanceServerOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Source, "source", o.Source,
+		"Where audit events are read from: file follows --audit-log-path, sample reads --sample-log-path once, "+
+			"webhook receives them from the audit webhook backend.")
+	fs.StringVar(&o.AuditLogPath, "audit-log-path", o.AuditLogPath, "Audit log of the kube-apiserver, used with --source=file.")
+	fs.StringVar(&o.SampleLogPath, "sample-log-path", o.SampleLogPath, "Pre-generated audit log, used with --source=sample.")
+	fs.StringVar(&o.KindCompositionFile, "kind-composition-file", o.KindCompositionFile, "File with the kinds to track.")
+	fs.BoolVar(&o.DiscoverKinds, "discover-kinds", o.DiscoverKinds,
+		"Track the kinds of the resources that appear in the audit events, in addition to the kind composition file.")
+	fs.StringSliceVar(&o.IncludeKinds, "include-kinds", o.IncludeKinds,
+		"Kinds, plurals or plural.group names to discover, all if empty. Used with --discover-kinds.")
+	fs.StringSliceVar(&o.ExcludeKinds, "exclude-kinds", o.ExcludeKinds,
+		"Kinds, plurals or plural.group names that are neither discovered nor ingested. Used with --discover-kinds.")
+	fs.DurationVar(&o.PollInterval, "poll-interval", o.PollInterval, "How often the audit log and the kind composition file are checked.")
+	fs.DurationVar(&o.StallThreshold, "ingestion-stall-threshold", o.StallThreshold,
+		"The provenance-ingestion health check fails when the audit log was not read for longer than this.")
+	fs.StringVar(&o.RedactionSalt, "redaction-salt", o.RedactionSalt,
+		"Salt of the hashes of redacted values, keeps them stable across restarts. Defaults to the REDACTION_SALT environment variable.")
+	fs.StringVar(&o.RedactionSaltFile, "redaction-salt-file", o.RedactionSaltFile,
+		"File with the salt of the hashes of redacted values, e.g. a mounted Secret. Can not be used with --redaction-salt.")
+}
+
+// redactionSalt returns the salt of --redaction-salt or --redaction-salt-file,
+// or of the REDACTION_SALT environment variable without either of them.
+func (o ProvenanceServerOptions) redactionSalt() (string, error) {
+	switch {
+	case o.RedactionSalt != "" && o.RedactionSaltFile != "":
+		return "", fmt.Errorf("--redaction-salt and --redaction-salt-file can not be used together")
+	case o.RedactionSaltFile != "":
+		salt, err := ioutil.ReadFile(o.RedactionSaltFile)
+		if err != nil {
+			return "", fmt.Errorf("--redaction-salt-file: %v", err)
+		}
+		if strings.TrimSpace(string(salt)) == "" {
+			return "", fmt.Errorf("--redaction-salt-file: %s is empty", o.RedactionSaltFile)
+		}
+		return strings.TrimSpace(string(salt)), nil
+	case o.RedactionSalt != "":
+		return o.RedactionSalt, nil
+	}
+	return os.Getenv("REDACTION_SALT"), nil
+}
+
 // NewCommandStartProvenanceServer provides a CLI handler for 'start master' command
 // with a default ProvenanceServerOptions.
 func NewCommandStartProvenanceServer(defaults *ProvenanceServerOptions, stopCh <-chan struct{}) *cobra.Command {
@@ -54,6 +121,7 @@ func NewCommandStartProvenanceServer(defaults *ProvenanceServerOptions, stopCh <
 
 	flags := cmd.Flags()
 	o.RecommendedOptions.AddFlags(flags)
+	o.AddFlags(flags)
 
 	return cmd
 }
@@ -61,6 +129,42 @@ func NewCommandStartProvenanceServer(defaults *ProvenanceServerOptions, stopCh <
 func (o ProvenanceServerOptions) Validate(args []string) error {
 	errors := []error{}
 	errors = append(errors, o.RecommendedOptions.Validate()...)
+	switch o.Source {
+	case apiserver.SourceFile:
+		if o.AuditLogPath == "" {
+			errors = append(errors, fmt.Errorf("--audit-log-path is required with --source=file"))
+		}
+	case apiserver.SourceSample:
+		if o.SampleLogPath == "" {
+			errors = append(errors, fmt.Errorf("--sample-log-path is required with --source=sample"))
+		}
+	case apiserver.SourceWebhook:
+	default:
+		errors = append(errors, fmt.Errorf("--source must be one of %s, %s or %s, got %q",
+			apiserver.SourceFile, apiserver.SourceSample, apiserver.SourceWebhook, o.Source))
+	}
+	if o.PollInterval <= 0 {
+		errors = append(errors, fmt.Errorf("--poll-interval must be greater than zero"))
+	}
+	if o.StallThreshold <= o.PollInterval {
+		errors = append(errors, fmt.Errorf("--ingestion-stall-threshold must be greater than --poll-interval"))
+	}
+	if o.KindCompositionFile == "" {
+		if !o.DiscoverKinds {
+			errors = append(errors, fmt.Errorf("--kind-composition-file is required without --discover-kinds"))
+		}
+	} else if _, err := os.Stat(o.KindCompositionFile); err != nil {
+		errors = append(errors, fmt.Errorf("--kind-composition-file: %v", err))
+	}
+	if !o.DiscoverKinds && (len(o.IncludeKinds) > 0 || len(o.ExcludeKinds) > 0) {
+		errors = append(errors, fmt.Errorf("--include-kinds and --exclude-kinds are used with --discover-kinds"))
+	}
+	if salt, err := o.redactionSalt(); err != nil {
+		errors = append(errors, err)
+	} else if salt == "" {
+		fmt.Fprintln(o.StdErr, "No redaction salt is set, the hashes of redacted values change when the server restarts. "+
+			"Set --redaction-salt or --redaction-salt-file to keep them.")
+	}
 	return utilerrors.NewAggregate(errors)
 }
 
@@ -79,9 +183,25 @@ func (o *ProvenanceServerOptions) Config() (*apiserver.Config, error) {
 		return nil, err
 	}
 
+	salt, err := o.redactionSalt()
+	if err != nil {
+		return nil, err
+	}
+
 	config := &apiserver.Config{
 		GenericConfig: serverConfig,
-		ExtraConfig:   apiserver.ExtraConfig{},
+		ExtraConfig: apiserver.ExtraConfig{
+			Source:              o.Source,
+			AuditLogPath:        o.AuditLogPath,
+			SampleLogPath:       o.SampleLogPath,
+			KindCompositionFile: o.KindCompositionFile,
+			PollInterval:        o.PollInterval,
+			StallThreshold:      o.StallThreshold,
+			DiscoverKinds:       o.DiscoverKinds,
+			IncludeKinds:        o.IncludeKinds,
+			ExcludeKinds:        o.ExcludeKinds,
+			RedactionSalt:       salt,
+		},
 	}
 	return config, nil
 }
@@ -96,5 +216,11 @@ func (o ProvenanceServerOptions) RunProvenanceServer(stopCh <-chan struct{}) err
 	if err != nil {
 		return err
 	}
-	return server.GenericAPIServer.PrepareRun().Run(stopCh)
+	err = server.GenericAPIServer.PrepareRun().Run(stopCh)
+	// Run returns once stopCh is closed, give the collector the chance
+	// to flush its checkpoints before the process exits
+	if server.Collector != nil {
+		server.Collector.Wait()
+	}
+	return err
 }
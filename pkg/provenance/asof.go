@@ -0,0 +1,55 @@
+package provenance
+
+import (
+	"strings"
+	"time"
+)
+
+// the format of Spec.Timestamp, the times of the audit events are stored in
+// UTC whatever the time zone of the server
+const timestampFormat = "2006-01-02 15:04:05"
+
+// parseTime parses value, the query parameter param, as an RFC3339 time or
+// as a duration relative to now, e.g. -2h or -90m. A space in place of the
+// + of a positive offset is accepted, that is what an unencoded + in a query
+// string is decoded to.
+func parseTime(param, value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, strings.Replace(value, " ", "+", 1)); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, newBadRequestError(param, value,
+			"Could not parse %s query parameter %q, want an RFC3339 time or a relative time such as -2h", param, value)
+	}
+	return now.Add(d), nil
+}
+
+// VersionAsOf returns the version that was in effect at the instant value,
+// the query parameter param, an RFC3339 time or a time relative to now. That
+// is the last version recorded at or before the instant, a deletion if the
+// object was deleted by then. Returns a NotFound error if the object did not
+// exist yet.
+func (o ObjectLineage) VersionAsOf(param, value string, now time.Time) (Spec, error) {
+	t, err := parseTime(param, value, now)
+	if err != nil {
+		return Spec{}, err
+	}
+	var inEffect *Spec
+	for _, spec := range getSpecsInOrder(o) {
+		timestamp, err := time.Parse(timestampFormat, spec.Timestamp)
+		if err != nil || timestamp.After(t) {
+			break
+		}
+		spec := spec
+		inEffect = &spec
+	}
+	if inEffect == nil {
+		return Spec{}, newNotFoundError(param, value, "No version recorded at or before %s",
+			t.UTC().Format(timestampFormat))
+	}
+	return *inEffect, nil
+}
@@ -0,0 +1,91 @@
+package provenance
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// Tests that VersionAsOf resolves absolute and relative times to the version
+// that was in effect at that instant.
+func TestVersionAsOf(t *testing.T) {
+	objLineage, _ := buildLineage()
+	timestamps := []string{"2018-08-05 00:16:20", "2018-08-05 01:00:00", "2018-08-05 02:30:00", "2018-08-06 10:00:00", "2018-08-07 09:15:00"}
+	for i, timestamp := range timestamps {
+		spec := objLineage[i+1]
+		spec.Timestamp = timestamp
+		objLineage[i+1] = spec
+	}
+	deleted := objLineage[5]
+	deleted.Deleted = true
+	objLineage[5] = deleted
+	now, _ := time.Parse(time.RFC3339, "2018-08-05T03:00:00Z")
+
+	tests := []struct {
+		asOf    string
+		version int
+		deleted bool
+	}{
+		{"2018-08-05T00:16:20Z", 1, false},
+		{"2018-08-05T00:59:59Z", 1, false},
+		{"2018-08-05T01:00:00Z", 2, false},
+		{"2018-08-06T12:00:00+02:00", 4, false},
+		{"2018-08-06T12:00:00 02:00", 4, false},
+		{"2018-08-08T00:00:00Z", 5, true},
+		{"-1h", 2, false},
+		{"-10m", 3, false},
+	}
+	for _, test := range tests {
+		spec, err := objLineage.VersionAsOf("asOf", test.asOf, now)
+		if err != nil {
+			t.Errorf("Error for TestVersionAsOf() %s was incorrect, got: %v, want: none.\n", test.asOf, err)
+			continue
+		}
+		if spec.Version != test.version || spec.Deleted != test.deleted {
+			t.Errorf("Version for TestVersionAsOf() %s was incorrect, got: %d (deleted %t), want: %d (deleted %t).\n",
+				test.asOf, spec.Version, spec.Deleted, test.version, test.deleted)
+		}
+	}
+
+	errors := []struct {
+		asOf   string
+		reason ErrorReason
+	}{
+		{"2018-08-05T00:16:19Z", ErrorReasonNotFound},
+		{"-3h", ErrorReasonNotFound},
+		{"yesterday", ErrorReasonBadRequest},
+		{"2018-08-05", ErrorReasonBadRequest},
+	}
+	for _, test := range errors {
+		_, err := objLineage.VersionAsOf("asOf", test.asOf, now)
+		if reason := ReasonForError(err); reason != test.reason {
+			t.Errorf("Error for TestVersionAsOf() %s was incorrect, got: %s (%v), want: %s.\n", test.asOf, reason, err, test.reason)
+		}
+	}
+}
+
+// Tests that the time of an event is stored in UTC on a server that is not
+// in UTC, audit timestamps are decoded to local time.
+func TestVersionAsOfLocalTime(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("IST", 5*3600+1800)
+	defer func() { time.Local = local }()
+
+	var event Event
+	eventJson := `{"verb":"create","user":{"username":"alice"},"objectRef":{"resource":"postgreses","namespace":"asof-local"},"requestObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"tz-client"},"spec":{"replicas":1}},"requestReceivedTimestamp":"2018-08-05T00:16:20.000000Z"}`
+	if err := json.Unmarshal([]byte(eventJson), &event); err != nil {
+		t.Fatalf("Could not parse test event: %s", err)
+	}
+	processEvent(&event)
+	provObj := FindProvenanceObject("postgreses", "asof-local", "tz-client")
+	if provObj == nil {
+		t.Fatalf("No provenance recorded for tz-client")
+	}
+	if got := provObj.ObjectFullHistory[1].Timestamp; got != "2018-08-05 00:16:20" {
+		t.Errorf("Timestamp for TestVersionAsOfLocalTime() was incorrect, got: %s, want: 2018-08-05 00:16:20.\n", got)
+	}
+	now, _ := time.Parse(time.RFC3339, "2018-08-05T03:00:00Z")
+	if spec, err := provObj.ObjectFullHistory.VersionAsOf("asOf", "2018-08-05T00:20:00Z", now); err != nil || spec.Version != 1 {
+		t.Errorf("Version for TestVersionAsOfLocalTime() was incorrect, got: %d %v, want: 1.\n", spec.Version, err)
+	}
+}
@@ -0,0 +1,92 @@
+package provenance
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Collector reads audit events into the provenance lineages until its
+// context is cancelled. It remembers how far it got in each log, so it can
+// be started again without reading an event twice.
+type Collector struct {
+	LogPath      string
+	PollInterval time.Duration
+	// read the log once instead of following it, for the static sample log
+	Once bool
+
+	lock sync.Mutex
+	// audit log path -> offset after the last event that was read
+	checkpoints map[string]int64
+	wg          sync.WaitGroup
+	// guarded by lock, reported by Status
+	state collectorState
+}
+
+func NewCollector(logPath string, pollInterval time.Duration, once bool) *Collector {
+	return &Collector{
+		LogPath:      logPath,
+		PollInterval: pollInterval,
+		Once:         once,
+		checkpoints:  make(map[string]int64),
+	}
+}
+
+// Start runs the collector in a new goroutine. Use Wait to block until it
+// has stopped and flushed its checkpoints.
+func (c *Collector) Start(ctx context.Context) {
+	c.wg.Add(1)
+	c.setRunning()
+	go func() {
+		defer c.wg.Done()
+		defer c.recordStop()
+		c.Run(ctx)
+	}()
+}
+
+// Wait blocks until every Run started with Start has returned.
+func (c *Collector) Wait() {
+	c.wg.Wait()
+}
+
+// Run reads the audit log every PollInterval until ctx is cancelled. A log
+// that is missing or unreadable is retried on the next tick.
+func (c *Collector) Run(ctx context.Context) {
+	fmt.Println("Inside Collector.Run")
+	done := false
+	for {
+		if !done {
+			offset, err := parse(ctx, c.LogPath, c.Checkpoint(c.LogPath))
+			c.flush(c.LogPath, offset)
+			c.recordRead(err)
+			if err != nil {
+				fmt.Printf("Error collecting provenance from %s: %s\n", c.LogPath, err)
+			} else {
+				done = c.Once
+			}
+		}
+		select {
+		case <-ctx.Done():
+			fmt.Printf("Stopped collecting provenance from %s at offset %d\n", c.LogPath, c.Checkpoint(c.LogPath))
+			return
+		case <-time.After(c.PollInterval):
+		}
+	}
+}
+
+// Checkpoint returns the offset up to which the log at logPath was read.
+func (c *Collector) Checkpoint(logPath string) int64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.checkpoints[logPath]
+}
+
+func (c *Collector) flush(logPath string, offset int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.checkpoints == nil {
+		c.checkpoints = make(map[string]int64)
+	}
+	c.checkpoints[logPath] = offset
+}
@@ -0,0 +1,78 @@
+package provenance
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func collectorTestEvent(version string) string {
+	return `{"verb":"update","user":{"username":"system:admin"},"objectRef":{"resource":"postgreses","namespace":"collector","name":"coll-client"},"requestObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"coll-client"},"spec":{"image":"postgres:` + version + `"}},"requestReceivedTimestamp":"2018-08-05T00:16:20.000000Z"}` + "\n"
+}
+
+func appendToLog(t *testing.T, logPath, data string) {
+	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Could not open test log: %s", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatalf("Could not write test log: %s", err)
+	}
+}
+
+func collectorVersions() int {
+	StoreLock.RLock()
+	defer StoreLock.RUnlock()
+	provObj := FindProvenanceObject("postgreses", "collector", "coll-client")
+	if provObj == nil {
+		return 0
+	}
+	return len(provObj.ObjectFullHistory)
+}
+
+// Runs the collector until the condition holds or a second has passed.
+func runCollector(c *Collector, until func() bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.Start(ctx)
+	for i := 0; i < 100 && !until(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	c.Wait()
+}
+
+// Tests that the collector stops when its context is cancelled, and that it
+// continues after its checkpoint when started again.
+func TestCollectorRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "collector")
+	if err != nil {
+		t.Fatalf("Could not create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	logPath := filepath.Join(dir, "audit.log")
+
+	c := NewCollector(logPath, 10*time.Millisecond, false)
+
+	// the log does not exist yet, which must not stop the collector
+	runCollector(c, func() bool { return false })
+	if checkpoint := c.Checkpoint(logPath); checkpoint != 0 {
+		t.Errorf("Checkpoint for TestCollectorRestart() was incorrect, got: %d, want: 0.\n", checkpoint)
+	}
+
+	appendToLog(t, logPath, collectorTestEvent("9.3")+collectorTestEvent("9.4"))
+	runCollector(c, func() bool { return collectorVersions() == 2 })
+	checkpoint := c.Checkpoint(logPath)
+	if info, _ := os.Stat(logPath); checkpoint != info.Size() {
+		t.Errorf("Checkpoint for TestCollectorRestart() was incorrect, got: %d, want: %d.\n", checkpoint, info.Size())
+	}
+
+	appendToLog(t, logPath, collectorTestEvent("10.1"))
+	runCollector(c, func() bool { return collectorVersions() == 3 })
+	if versions := collectorVersions(); versions != 3 {
+		t.Errorf("Versions for TestCollectorRestart() were incorrect, got: %d, want: 3.\n", versions)
+	}
+}
@@ -0,0 +1,68 @@
+package provenance
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// CompositionWatcher reloads the kind compositions file when its content
+// changes. A ConfigMap volume is updated by swapping a symlink, so the file
+// is compared by content rather than relying on file system events.
+type CompositionWatcher struct {
+	FilePath     string
+	PollInterval time.Duration
+	// called after a changed file was loaded, with the previous and the
+	// new kind -> plural maps
+	OnReload func(old, new map[string]string)
+
+	content []byte
+}
+
+func NewCompositionWatcher(filePath string, pollInterval time.Duration, onReload func(old, new map[string]string)) *CompositionWatcher {
+	return &CompositionWatcher{
+		FilePath:     filePath,
+		PollInterval: pollInterval,
+		OnReload:     onReload,
+	}
+}
+
+// Run checks the file every PollInterval until ctx is cancelled. The file
+// that was read at startup is taken as the current content.
+func (w *CompositionWatcher) Run(ctx context.Context) {
+	w.content, _ = ioutil.ReadFile(w.FilePath)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.PollInterval):
+		}
+		if err := w.check(); err != nil {
+			fmt.Printf("Keeping the current kind compositions: %s\n", err)
+		}
+	}
+}
+
+func (w *CompositionWatcher) check() error {
+	content, err := ioutil.ReadFile(w.FilePath)
+	if err != nil {
+		return fmt.Errorf("Error reading file:%s", err)
+	}
+	if bytes.Equal(content, w.content) {
+		return nil
+	}
+	old := TrackedKinds()
+	if err := ReadKindCompositionFile(w.FilePath); err != nil {
+		return err
+	}
+	// only remember the content once it was loaded, so a broken file is
+	// reported on every check until it is fixed
+	w.content = content
+	fmt.Printf("Reloaded kind compositions from %s\n", w.FilePath)
+	if w.OnReload != nil {
+		w.OnReload(old, TrackedKinds())
+	}
+	return nil
+}
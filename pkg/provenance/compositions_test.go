@@ -0,0 +1,98 @@
+package provenance
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Returns a function that puts the current kind maps back.
+func restoreKindMaps() func() {
+	oldPlurals, oldVersions, oldCompositions, oldRedaction := KindPluralMap, kindVersionMap, compositionMap, redactionMap
+	oldScopes, oldShortNames := clusterScopedKinds, shortNamesMap
+	return func() {
+		KindPluralMap, kindVersionMap, compositionMap, redactionMap = oldPlurals, oldVersions, oldCompositions, oldRedaction
+		clusterScopedKinds, shortNamesMap = oldScopes, oldShortNames
+	}
+}
+
+func writeCompositions(t *testing.T, filePath, content string) {
+	if err := ioutil.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("Could not write %s: %s", filePath, err)
+	}
+}
+
+// Tests that a changed compositions file replaces the kinds, and that a
+// broken file leaves the current kinds in place.
+func TestCompositionWatcherReload(t *testing.T) {
+	defer restoreKindMaps()()
+
+	dir, err := ioutil.TempDir("", "compositions")
+	if err != nil {
+		t.Fatalf("Could not create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "kind_compositions.yaml")
+	writeCompositions(t, filePath, "- kind: Postgres\n  plural: postgreses\n")
+	if err := ReadKindCompositionFile(filePath); err != nil {
+		t.Fatalf("ReadKindCompositionFile() for TestCompositionWatcherReload() returned an error: %s", err)
+	}
+
+	reloads := 0
+	var reloaded map[string]string
+	w := NewCompositionWatcher(filePath, time.Millisecond, func(old, new map[string]string) {
+		reloads++
+		reloaded = new
+	})
+	w.content, _ = ioutil.ReadFile(filePath)
+
+	writeCompositions(t, filePath, "- kind: Postgres\n  plural: postgreses\n- kind: Moodle\n  plural: moodles\n")
+	if err := w.check(); err != nil {
+		t.Errorf("Reload for TestCompositionWatcherReload() returned an error: %s\n", err)
+	}
+	if reloads != 1 || reloaded["Moodle"] != "moodles" || kindForPlural("moodles") != "Moodle" {
+		t.Errorf("Kinds after reload for TestCompositionWatcherReload() were incorrect, got: %v, want: Postgres and Moodle.\n", reloaded)
+	}
+
+	writeCompositions(t, filePath, "- kind: Moodle\n  plural: moodles\n- kind: Postgres\n  plural: moodles\n")
+	if err := w.check(); err == nil {
+		t.Errorf("Reload for TestCompositionWatcherReload() accepted two kinds with the same plural.\n")
+	}
+	if reloads != 1 || kindForPlural("postgreses") != "Postgres" {
+		t.Errorf("Kinds after a broken file for TestCompositionWatcherReload() were incorrect, got: %v, want: Postgres and Moodle.\n", TrackedKinds())
+	}
+
+	if err := w.check(); err == nil {
+		t.Errorf("Check for TestCompositionWatcherReload() did not report the broken file again.\n")
+	}
+}
+
+// Tests that the scope and short names of a kind are read from the
+// compositions file, and that an unknown scope is refused.
+func TestClusterScopedKinds(t *testing.T) {
+	defer restoreKindMaps()()
+
+	dir, err := ioutil.TempDir("", "compositions")
+	if err != nil {
+		t.Fatalf("Could not create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "kind_compositions.yaml")
+	writeCompositions(t, filePath, "- kind: Postgres\n  plural: postgreses\n  shortNames: [pgprov]\n- kind: ClusterRole\n  plural: clusterroles\n  scope: Cluster\n")
+	if err := ReadKindCompositionFile(filePath); err != nil {
+		t.Fatalf("ReadKindCompositionFile() for TestClusterScopedKinds() returned an error: %s", err)
+	}
+	if scopes := ClusterScopedKinds(); !scopes["ClusterRole"] || scopes["Postgres"] {
+		t.Errorf("Cluster scoped kinds for TestClusterScopedKinds() were incorrect, got: %v, want: ClusterRole.\n", scopes)
+	}
+	if shortNames := KindShortNames()["Postgres"]; len(shortNames) != 1 || shortNames[0] != "pgprov" {
+		t.Errorf("Short names of Postgres for TestClusterScopedKinds() were incorrect, got: %v, want: pgprov.\n", shortNames)
+	}
+
+	writeCompositions(t, filePath, "- kind: ClusterRole\n  plural: clusterroles\n  scope: Global\n")
+	if err := ReadKindCompositionFile(filePath); err == nil {
+		t.Errorf("ReadKindCompositionFile() for TestClusterScopedKinds() accepted the scope Global.\n")
+	}
+}
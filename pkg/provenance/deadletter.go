@@ -0,0 +1,338 @@
+package provenance
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apiserver/pkg/apis/audit/v1beta1"
+)
+
+// Number of dead letters that are kept. When the store is full the oldest
+// letter is dropped, the totals keep counting.
+const deadLetterCapacity = 500
+
+// An audit event that was rejected, or only partially parsed, during ingestion.
+// The event itself is not kept, it may hold secrets that were not redacted,
+// only where it was read and the object it was about.
+type DeadLetter struct {
+	// the audit log file, or webhook for events that were posted to the server
+	Source string
+	// byte offset of the event in Source
+	Offset int64
+	Reason string
+	// the version was stored, but some of the event was skipped
+	Partial bool
+	// the resource of the event was not tracked when it was read
+	Untracked bool
+	// when the event was rejected
+	Timestamp string
+
+	// verb and objectRef of the event, empty if it could not be parsed
+	Verb        string
+	Resource    string
+	Namespace   string
+	Name        string
+	Subresource string
+
+	// requestReceivedTimestamp of the event, in the format of the versions.
+	// An untracked letter is read again from Source when it is reprocessed.
+	requestTimestamp string
+}
+
+type deadLetterStore struct {
+	lock    sync.Mutex
+	letters []DeadLetter
+	total   int
+	dropped int
+}
+
+var (
+	deadLetters = &deadLetterStore{letters: make([]DeadLetter, 0)}
+	// writes to resources that are not tracked are kept apart, they are
+	// frequent and would push the other letters out
+	untrackedLetters = &deadLetterStore{letters: make([]DeadLetter, 0)}
+)
+
+func (d *deadLetterStore) add(letter DeadLetter) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	fmt.Printf("Dead letter at %s:%d: %s\n", letter.Source, letter.Offset, letter.Reason)
+	if len(d.letters) == deadLetterCapacity {
+		d.letters = d.letters[1:]
+		d.dropped++
+	}
+	d.letters = append(d.letters, letter)
+	d.total++
+}
+
+func newDeadLetter(source string, offset int64, event *Event, partial bool, reason string) DeadLetter {
+	letter := DeadLetter{
+		Source:    source,
+		Offset:    offset,
+		Reason:    reason,
+		Partial:   partial,
+		Timestamp: time.Now().UTC().Format(timestampFormat),
+	}
+	if event != nil {
+		letter.Verb = event.Verb
+		if ref := event.ObjectRef; ref != nil {
+			letter.Resource = ref.Resource
+			letter.Namespace = ref.Namespace
+			letter.Name = ref.Name
+			letter.Subresource = ref.Subresource
+		}
+		if letter.Name == "" && event.RequestObject != nil {
+			letter.Name = nameFromRequestObject(event.RequestObject.Raw)
+		}
+	}
+	return letter
+}
+
+func addDeadLetter(source string, offset int64, event *Event, partial bool, reason string) {
+	deadLetters.add(newDeadLetter(source, offset, event, partial, reason))
+}
+
+// Verbs of the requests that change objects, the other events of an
+// untracked resource are not worth keeping.
+var writeVerbs = map[string]bool{"create": true, "update": true, "patch": true, "delete": true, "deletecollection": true}
+
+// trackedResource reports whether the events of the resource of ref are
+// ingested. With discovery, the include and exclude lists decide. Without
+// it, every resource is ingested until kinds are configured, and then
+// only their plurals.
+func trackedResource(ref *v1beta1.ObjectReference) bool {
+	if discovery != nil {
+		return true
+	}
+	KindLock.RLock()
+	defer KindLock.RUnlock()
+	if len(KindPluralMap) == 0 {
+		return true
+	}
+	for _, plural := range KindPluralMap {
+		if plural == ref.Resource {
+			return true
+		}
+	}
+	return false
+}
+
+// ingestEvent parses one line of the audit log and adds it to the lineages.
+// Events that can not be used are kept as dead letters instead of being lost.
+func ingestEvent(eventJson []byte, source string, offset int64) {
+	eventsRead.Inc()
+	var event Event
+	err := json.Unmarshal(eventJson, &event)
+	if err != nil {
+		s := fmt.Sprintf("Problem parsing event's json %s", err)
+		fmt.Println(s)
+		eventsFailed.WithLabelValues(reasonInvalidJSON).Inc()
+		addDeadLetter(source, offset, nil, false, s)
+		return
+	}
+	if event.ObjectRef == nil {
+		eventsFailed.WithLabelValues(reasonNoObjectRef).Inc()
+		addDeadLetter(source, offset, &event, false, "Event has no objectRef")
+		return
+	}
+	if !trackedResource(event.ObjectRef) {
+		eventsSkipped.WithLabelValues(reasonUntracked).Inc()
+		if writeVerbs[event.Verb] && (event.ResponseStatus == nil || event.ResponseStatus.Code < 400) {
+			letter := newDeadLetter(source, offset, &event, false, fmt.Sprintf("Resource %s is not tracked", event.ObjectRef.Resource))
+			letter.Untracked = true
+			letter.requestTimestamp = event.RequestReceivedTimestamp.UTC().Format(timestampFormat)
+			untrackedLetters.add(letter)
+		}
+		return
+	}
+	eventsParsed.Inc()
+	StoreLock.Lock()
+	problems, err := processEvent(&event)
+	StoreLock.Unlock()
+	recordIngestionLag(&event)
+	if err != nil {
+		fmt.Println(err)
+		eventsFailed.WithLabelValues(reasonBadSelector).Inc()
+		addDeadLetter(source, offset, &event, false, err.Error())
+		return
+	}
+	if len(problems) > 0 {
+		eventsFailed.WithLabelValues(reasonPartial).Inc()
+		addDeadLetter(source, offset, &event, true, strings.Join(problems, "; "))
+	}
+}
+
+// DeadLetterCount returns the number of stored dead letters, and the number
+// of events rejected since the server started.
+func DeadLetterCount() (int, int) {
+	stored, total := 0, 0
+	for _, d := range []*deadLetterStore{deadLetters, untrackedLetters} {
+		d.lock.Lock()
+		stored += len(d.letters)
+		total += d.total
+		d.lock.Unlock()
+	}
+	return stored, total
+}
+
+// DeadLetters returns the string representation of the dead letter store.
+func DeadLetters() string {
+	return DeadLettersString(DeadLetterStores())
+}
+
+// DeadLettersString returns the string representation of the dead letters
+// and the writes to untracked resources.
+func DeadLettersString(rejected, untracked DeadLetterSnapshot) string {
+	var b strings.Builder
+	rejected.write(&b, "Dead letters")
+	untracked.write(&b, "Writes to untracked resources")
+	return b.String()
+}
+
+// DeadLetterSnapshot is the content of a dead letter store at one time.
+type DeadLetterSnapshot struct {
+	Letters []DeadLetter
+	// letters added since the server started, and dropped when it was full
+	Total   int
+	Dropped int
+}
+
+// DeadLetterStores returns the dead letters, and the writes to resources
+// that were not tracked.
+func DeadLetterStores() (DeadLetterSnapshot, DeadLetterSnapshot) {
+	return deadLetters.snapshot(), untrackedLetters.snapshot()
+}
+
+func (d *deadLetterStore) snapshot() DeadLetterSnapshot {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	letters := append([]DeadLetter{}, d.letters...)
+	return DeadLetterSnapshot{Letters: letters, Total: d.total, Dropped: d.dropped}
+}
+
+func (s DeadLetterSnapshot) write(b *strings.Builder, title string) {
+	fmt.Fprintf(b, "%s: %d stored, %d total, %d dropped\n", title, len(s.Letters), s.Total, s.Dropped)
+	for _, letter := range s.Letters {
+		kind := "rejected"
+		switch {
+		case letter.Partial:
+			kind = "partial"
+		case letter.Untracked:
+			kind = "untracked"
+		}
+		fmt.Fprintf(b, "%s %s:%d (%s): %s\n", letter.Timestamp, letter.Source, letter.Offset, kind, letter.Reason)
+		if letter.Verb != "" || letter.Resource != "" {
+			fmt.Fprintf(b, "  %s %s\n", letter.Verb, letter.objectString())
+		}
+	}
+}
+
+func (letter DeadLetter) objectString() string {
+	object := letter.Resource
+	if letter.Subresource != "" {
+		object += "/" + letter.Subresource
+	}
+	if letter.Namespace != "" {
+		object += " " + letter.Namespace + "/" + letter.Name
+	} else if letter.Name != "" {
+		object += " " + letter.Name
+	}
+	return object
+}
+
+// ReprocessDeadLetters ingests the writes to resources that were not
+// tracked when they were read and are tracked now, for example after a
+// kind was added to the kind compositions. The other letters can not turn
+// out differently and stay in the store. A letter about an object that
+// already has a later version stays too, ingesting it now would put it
+// after that version. The events are read again from their audit log, a
+// letter whose event is not there any more is dropped. Returns the number
+// of events that were ingested.
+func ReprocessDeadLetters() int {
+	d := untrackedLetters
+	d.lock.Lock()
+	letters := d.letters
+	d.letters = make([]DeadLetter, 0)
+	d.lock.Unlock()
+
+	reprocessed := 0
+	kept := make([]DeadLetter, 0)
+	for _, letter := range letters {
+		ref := &v1beta1.ObjectReference{Resource: letter.Resource}
+		if !trackedResource(ref) || hasLaterVersion(letter) {
+			kept = append(kept, letter)
+			continue
+		}
+		eventJson, ok := letter.readEvent()
+		if !ok {
+			fmt.Printf("Dropped dead letter at %s:%d, its event can not be read again\n", letter.Source, letter.Offset)
+			continue
+		}
+		ingestEvent(eventJson, letter.Source, letter.Offset)
+		reprocessed++
+	}
+
+	//letters that were added while reprocessing come after the kept ones
+	d.lock.Lock()
+	d.letters = append(kept, d.letters...)
+	d.lock.Unlock()
+	return reprocessed
+}
+
+// hasLaterVersion reports whether the object of the letter, or any object
+// of its resource and namespace for a deletecollection, has a version
+// recorded after the event of the letter.
+func hasLaterVersion(letter DeadLetter) bool {
+	StoreLock.RLock()
+	defer StoreLock.RUnlock()
+	for _, provObj := range AllProvenanceObjects {
+		if provObj.ResourcePlural != letter.Resource || provObj.Namespace != letter.Namespace ||
+			(letter.Name != "" && provObj.Name != letter.Name) {
+			continue
+		}
+		for _, spec := range provObj.ObjectFullHistory {
+			if spec.Timestamp > letter.requestTimestamp {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readEvent reads the event of the letter at its offset in its audit log.
+// Returns false for an event that was posted to the webhook, or if the
+// event at the offset is not the one of the letter, e.g. after the log was
+// rotated.
+func (letter DeadLetter) readEvent() ([]byte, bool) {
+	if letter.Source == webhookSource {
+		return nil, false
+	}
+	log, err := os.Open(letter.Source)
+	if err != nil {
+		return nil, false
+	}
+	defer log.Close()
+	if _, err := log.Seek(letter.Offset, io.SeekStart); err != nil {
+		return nil, false
+	}
+	line, err := bufio.NewReader(log).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, false
+	}
+	var event Event
+	if err := json.Unmarshal(line, &event); err != nil || event.ObjectRef == nil {
+		return nil, false
+	}
+	read := newDeadLetter(letter.Source, letter.Offset, &event, false, letter.Reason)
+	if read.Verb != letter.Verb || read.objectString() != letter.objectString() ||
+		event.RequestReceivedTimestamp.UTC().Format(timestampFormat) != letter.requestTimestamp {
+		return nil, false
+	}
+	return line, true
+}
@@ -0,0 +1,113 @@
+package provenance
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Tests that broken and partially parsed events end up in the dead letter
+// store with their offsets, and that only rejected events are reprocessed.
+func TestDeadLetters(t *testing.T) {
+	deadLetters = &deadLetterStore{letters: make([]DeadLetter, 0)}
+
+	ingestEvent([]byte(`{"verb":"create",`), "test.log", 0)
+	ingestEvent([]byte(`{"verb":"create"}`), "test.log", 18)
+	ingestEvent([]byte(`{"verb":"create","objectRef":{"resource":"postgreses","namespace":"default"},"requestObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"dl-client"},"spec":{"image":"postgres:9.3","password":"SuperSecret1","resources":{"cpu":"1"}}},"requestReceivedTimestamp":"2018-08-05T00:16:20.000000Z"}`), "test.log", 36)
+	ingestEvent([]byte(`{"verb":"deletecollection","requestURI":"/apis/postgrescontroller.kubeplus/v1/namespaces/default/postgreses?labelSelector=team%20in%20a","objectRef":{"resource":"postgreses","namespace":"default"},"requestReceivedTimestamp":"2018-08-05T00:16:30.000000Z"}`), "test.log", 400)
+
+	stored, total := DeadLetterCount()
+	if stored != 4 || total != 4 {
+		t.Errorf("Dead letter count for TestDeadLetters() was incorrect, got: %d stored %d total, want: 4 stored 4 total.\n", stored, total)
+	}
+	output := DeadLetters()
+	for _, expected := range []string{
+		"test.log:0 (rejected): Problem parsing event's json",
+		"test.log:18 (rejected): Event has no objectRef",
+		"test.log:36 (partial): Attribute resources has an unsupported shape and was skipped",
+		"  create postgreses default/dl-client\n",
+		"test.log:400 (rejected): Could not parse the label selector of",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Dead letters output for TestDeadLetters() is missing %s, got: %s\n", expected, output)
+		}
+	}
+
+	//the event is not kept, it may hold secrets
+	if strings.Contains(output, "SuperSecret1") {
+		t.Errorf("Dead letters output for TestDeadLetters() has the event, got: %s\n", output)
+	}
+
+	if reprocessed := ReprocessDeadLetters(); reprocessed != 0 {
+		t.Errorf("Reprocessed count for TestDeadLetters() was incorrect, got: %d, want: 0.\n", reprocessed)
+	}
+	if stored, _ := DeadLetterCount(); stored != 4 {
+		t.Errorf("Dead letter count after reprocessing for TestDeadLetters() was incorrect, got: %d, want: 4.\n", stored)
+	}
+}
+
+// Tests that writes to resources that are not tracked are kept apart, and
+// that only those are read again from the log and ingested once their
+// resource is tracked, if that does not put them after a later version of
+// their object.
+func TestReprocessUntrackedDeadLetters(t *testing.T) {
+	oldPlurals := KindPluralMap
+	defer func() { KindPluralMap = oldPlurals }()
+	KindPluralMap = map[string]string{"EtcdCluster": "etcdclusters"}
+	deadLetters = &deadLetterStore{letters: make([]DeadLetter, 0)}
+	untrackedLetters = &deadLetterStore{letters: make([]DeadLetter, 0)}
+
+	logFile, err := ioutil.TempFile("", "kubeprovenance-audit")
+	if err != nil {
+		t.Fatalf("Could not create the audit log: %s", err)
+	}
+	defer os.Remove(logFile.Name())
+	var offset int64
+	ingest := func(eventJson string) {
+		logFile.WriteString(eventJson + "\n")
+		ingestEvent([]byte(eventJson), logFile.Name(), offset)
+		offset += int64(len(eventJson)) + 1
+	}
+	event := func(name, timestamp string) string {
+		return `{"verb":"create","objectRef":{"resource":"postgreses","namespace":"default"},"requestObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"` + name + `"},"spec":{"image":"postgres:9.3"}},"requestReceivedTimestamp":"` + timestamp + `"}`
+	}
+	ingest(event("untracked-client", "2018-08-05T00:16:20.000000Z"))
+	ingest(event("untracked-later", "2018-08-05T00:16:20.000000Z"))
+	ingest(`{"verb":"get","objectRef":{"resource":"postgreses","namespace":"default","name":"untracked-client"}}`)
+	//a letter whose event is not in the log any more is dropped
+	ingestEvent([]byte(event("untracked-rotated", "2018-08-05T00:16:20.000000Z")), logFile.Name(), 0)
+
+	if FindProvenanceObject("postgreses", "default", "untracked-client") != nil {
+		t.Errorf("Lineage for TestReprocessUntrackedDeadLetters() was incorrect, got: a lineage of an untracked resource.\n")
+	}
+	if stored, _ := DeadLetterCount(); stored != 3 {
+		t.Errorf("Dead letter count for TestReprocessUntrackedDeadLetters() was incorrect, got: %d, want: 3.\n", stored)
+	}
+	expected := logFile.Name() + ":0 (untracked): Resource postgreses is not tracked"
+	if output := DeadLetters(); !strings.Contains(output, expected) {
+		t.Errorf("Dead letters output for TestReprocessUntrackedDeadLetters() is missing %s, got: %s\n", expected, output)
+	}
+	if reprocessed := ReprocessDeadLetters(); reprocessed != 0 {
+		t.Errorf("Reprocessed count for TestReprocessUntrackedDeadLetters() was incorrect, got: %d, want: 0.\n", reprocessed)
+	}
+
+	KindPluralMap = map[string]string{"EtcdCluster": "etcdclusters", "Postgres": "postgreses"}
+	//untracked-later got a version after its letter was written
+	ingest(event("untracked-later", "2018-08-05T00:20:00.000000Z"))
+	if reprocessed := ReprocessDeadLetters(); reprocessed != 1 {
+		t.Errorf("Reprocessed count for TestReprocessUntrackedDeadLetters() was incorrect, got: %d, want: 1.\n", reprocessed)
+	}
+	if provObj := FindProvenanceObject("postgreses", "default", "untracked-client"); provObj == nil || len(provObj.ObjectFullHistory) != 1 {
+		t.Errorf("Lineage for TestReprocessUntrackedDeadLetters() was incorrect, got: %v, want: 1 version.\n", provObj)
+	}
+	if provObj := FindProvenanceObject("postgreses", "default", "untracked-later"); provObj == nil || len(provObj.ObjectFullHistory) != 1 {
+		t.Errorf("Lineage for TestReprocessUntrackedDeadLetters() was incorrect, got: %v, want: only the later version.\n", provObj)
+	}
+	if FindProvenanceObject("postgreses", "default", "untracked-rotated") != nil {
+		t.Errorf("Lineage for TestReprocessUntrackedDeadLetters() was incorrect, got: a lineage of an event that is not in the log.\n")
+	}
+	if stored, _ := DeadLetterCount(); stored != 1 {
+		t.Errorf("Dead letter count after reprocessing for TestReprocessUntrackedDeadLetters() was incorrect, got: %d, want: 1.\n", stored)
+	}
+}
@@ -0,0 +1,62 @@
+package provenance
+
+import (
+	"fmt"
+	"net/url"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// recordTombstone adds a version that marks the object as deleted.
+func recordTombstone(provObj *ProvenanceOfObject, event *Event, timestamp string) {
+	addTombstone(provObj, event.User.Username, timestamp, "")
+}
+
+func addTombstone(provObj *ProvenanceOfObject, actor, timestamp, changeSet string) {
+	newVersion := len(provObj.ObjectFullHistory) + 1
+	tombstone := *NewSpec()
+	tombstone.Version = newVersion
+	tombstone.Timestamp = timestamp
+	tombstone.Actor = actor
+	tombstone.Deleted = true
+	tombstone.ChangeSet = changeSet
+	provObj.ObjectFullHistory[newVersion] = tombstone
+	countVersion(provObj.ResourcePlural)
+}
+
+// processDeleteCollection expands a deletecollection request into a
+// tombstone for every tracked object of the resource and namespace that
+// exists and matches the label selector of the request. All tombstones
+// carry the auditID of the request as their change set. A label selector
+// that can not be parsed rejects the event, nothing is deleted.
+func processDeleteCollection(event *Event, timestamp string) error {
+	selector, err := deleteCollectionSelector(event.RequestURI)
+	if err != nil {
+		return fmt.Errorf("Could not parse the label selector of %s: %s", event.RequestURI, err)
+	}
+	changeSet := string(event.AuditID)
+	for _, provObj := range AllProvenanceObjects {
+		if provObj.ResourcePlural != event.ObjectRef.Resource || provObj.Namespace != event.ObjectRef.Namespace {
+			continue
+		}
+		latest, ok := latestSpec(provObj.ObjectFullHistory)
+		if !ok || latest.Deleted {
+			continue
+		}
+		if !selector.Matches(labels.Set(latest.Labels)) {
+			continue
+		}
+		addTombstone(provObj, event.User.Username, timestamp, changeSet)
+	}
+	return nil
+}
+
+// The label selector is passed as the labelSelector query parameter.
+// Without one the request deletes every object of the collection.
+func deleteCollectionSelector(requestURI string) (labels.Selector, error) {
+	u, err := url.Parse(requestURI)
+	if err != nil {
+		return nil, err
+	}
+	return labels.Parse(u.Query().Get("labelSelector"))
+}
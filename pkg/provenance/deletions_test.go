@@ -0,0 +1,44 @@
+package provenance
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+var deleteCollectionEvents = []string{
+	`{"verb":"create","user":{"username":"system:admin"},"objectRef":{"resource":"postgreses","namespace":"bulk"},"requestObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"bulk-1","labels":{"team":"a"}},"spec":{"image":"postgres:9.3"}},"requestReceivedTimestamp":"2018-08-05T00:16:20.000000Z"}`,
+	`{"verb":"create","user":{"username":"system:admin"},"objectRef":{"resource":"postgreses","namespace":"bulk"},"requestObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"bulk-2","labels":{"team":"b"}},"spec":{"image":"postgres:9.3"}},"requestReceivedTimestamp":"2018-08-05T00:16:21.000000Z"}`,
+	`{"verb":"create","user":{"username":"system:admin"},"objectRef":{"resource":"postgreses","namespace":"bulk"},"requestObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"bulk-3","labels":{"team":"a"}},"spec":{"image":"postgres:9.3"}},"requestReceivedTimestamp":"2018-08-05T00:16:22.000000Z"}`,
+	`{"verb":"delete","user":{"username":"system:admin"},"objectRef":{"resource":"postgreses","namespace":"bulk","name":"bulk-3"},"requestReceivedTimestamp":"2018-08-05T00:17:00.000000Z"}`,
+	`{"verb":"deletecollection","auditID":"b1a6b1c0","requestURI":"/apis/postgrescontroller.kubeplus/v1/namespaces/bulk/postgreses?labelSelector=team%3Da","user":{"username":"system:admin"},"objectRef":{"resource":"postgreses","namespace":"bulk"},"requestReceivedTimestamp":"2018-08-05T00:18:00.000000Z"}`,
+}
+
+// Tests that deletecollection only tombstones the objects that still exist
+// and match the label selector, all under the auditID as change set.
+func TestDeleteCollection(t *testing.T) {
+	for _, eventJson := range deleteCollectionEvents {
+		var event Event
+		if err := json.Unmarshal([]byte(eventJson), &event); err != nil {
+			t.Fatalf("Could not parse test event: %s", err)
+		}
+		processEvent(&event)
+	}
+
+	expected := map[string]string{
+		"bulk-1": "[2018-08-05 00:16:20: Version 1,\n2018-08-05 00:18:00: Version 2 (deleted)]\n",
+		"bulk-2": "[2018-08-05 00:16:21: Version 1]\n",
+		"bulk-3": "[2018-08-05 00:16:22: Version 1,\n2018-08-05 00:17:00: Version 2 (deleted)]\n",
+	}
+	for name, want := range expected {
+		provObj := FindProvenanceObject("postgreses", "bulk", name)
+		if provObj == nil {
+			t.Fatalf("No provenance recorded for %s", name)
+		}
+		if got := provObj.ObjectFullHistory.GetVersions(); got != want {
+			t.Errorf("Versions output of %s for TestDeleteCollection() was incorrect, got: %s, want: %s.\n", name, got, want)
+		}
+	}
+	if changeSet := FindProvenanceObject("postgreses", "bulk", "bulk-1").ObjectFullHistory[2].ChangeSet; changeSet != "b1a6b1c0" {
+		t.Errorf("Change set for TestDeleteCollection() was incorrect, got: %s, want: b1a6b1c0.\n", changeSet)
+	}
+}
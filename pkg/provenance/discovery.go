@@ -0,0 +1,249 @@
+package provenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A kind that was learned from the audit stream
+type discoveredKind struct {
+	Kind     string
+	Plural   string
+	Group    string
+	Endpoint string
+	// the events of the resource carry no namespace
+	ClusterScoped bool
+}
+
+type kindDiscovery struct {
+	// kind names, plurals or plural.group, e.g. Postgres, postgreses or
+	// postgreses.postgrescontroller.kubeplus
+	include []string
+	exclude []string
+	// called, in its own goroutine, after a kind was discovered
+	onDiscover func()
+}
+
+var (
+	// nil unless discovery was enabled
+	discovery *kindDiscovery
+	// plural.group -> kind, guarded by KindLock. Discovered kinds are kept
+	// when the kind compositions file is reloaded.
+	discoveredKinds = make(map[string]discoveredKind)
+)
+
+// EnableKindDiscovery makes ingestion learn the kinds of the resources
+// that appear in the audit events and track them as if they were listed
+// in the kind compositions file. Resources matched by exclude, or not
+// matched by a non empty include, are neither discovered nor ingested.
+func EnableKindDiscovery(include, exclude []string, onDiscover func()) {
+	discovery = &kindDiscovery{
+		include:    include,
+		exclude:    exclude,
+		onDiscover: onDiscover,
+	}
+}
+
+func (d *kindDiscovery) matches(list []string, plural, group, kind string) bool {
+	for _, entry := range list {
+		if strings.EqualFold(entry, plural) || strings.EqualFold(entry, qualifiedPlural(plural, group)) ||
+			(kind != "" && entry == kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// selects reports whether the include and exclude lists let the resource
+// through. kind is empty while it is not known yet.
+func (d *kindDiscovery) selects(plural, group, kind string) bool {
+	if d.matches(d.exclude, plural, group, kind) {
+		return false
+	}
+	return len(d.include) == 0 || d.matches(d.include, plural, group, kind)
+}
+
+func qualifiedPlural(plural, group string) string {
+	if group == "" {
+		return plural
+	}
+	return plural + "." + group
+}
+
+// discoverKind learns the kind of the resource of event, if discovery is
+// enabled and the request carries an object. Returns false if the event
+// belongs to a resource that must not be ingested.
+func discoverKind(event *Event) bool {
+	d := discovery
+	if d == nil {
+		return true
+	}
+	ref := event.ObjectRef
+	kind := ""
+	if event.RequestObject != nil && ref.Subresource == "" {
+		kind = kindOfRequestObject(event.RequestObject.Raw, ref.APIGroup)
+	}
+	if !d.selects(ref.Resource, ref.APIGroup, kind) {
+		return false
+	}
+	if kind == "" {
+		return true
+	}
+
+	KindLock.Lock()
+	added := addDiscoveredKind(discoveredKind{
+		Kind:     kind,
+		Plural:   ref.Resource,
+		Group:    ref.APIGroup,
+		Endpoint: endpointOf(ref.APIGroup, ref.APIVersion),
+		// the create of a namespaced object always names its namespace
+		ClusterScoped: ref.Namespace == "",
+	})
+	KindLock.Unlock()
+	if added && d.onDiscover != nil {
+		go d.onDiscover()
+	}
+	return true
+}
+
+// The kind of the request body, if its apiVersion belongs to group.
+// A Scale or DeleteOptions body has a group of its own and is ignored.
+func kindOfRequestObject(requestObjBytes []byte, group string) string {
+	var obj struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+	}
+	if err := json.Unmarshal(requestObjBytes, &obj); err != nil || obj.Kind == "" {
+		return ""
+	}
+	bodyGroup := ""
+	if i := strings.LastIndex(obj.APIVersion, "/"); i >= 0 {
+		bodyGroup = obj.APIVersion[:i]
+	}
+	if bodyGroup != group {
+		return ""
+	}
+	return obj.Kind
+}
+
+func endpointOf(group, version string) string {
+	if group == "" {
+		return "api/" + version
+	}
+	return "apis/" + group + "/" + version
+}
+
+// addDiscoveredKind adds k to the kind maps, unless its kind or plural is
+// already tracked. The caller holds KindLock. Returns true if k was added.
+func addDiscoveredKind(k discoveredKind) bool {
+	key := qualifiedPlural(k.Plural, k.Group)
+	if _, ok := discoveredKinds[key]; ok {
+		return false
+	}
+	discoveredKinds[key] = k
+	if !canTrack(KindPluralMap, k) {
+		fmt.Printf("Not tracking discovered kind %s (%s), the kind or its plural is already tracked\n", k.Kind, key)
+		return false
+	}
+	fmt.Printf("Discovered kind %s (%s)\n", k.Kind, key)
+
+	// the maps are copied, readers may still hold the current ones
+	newKindPluralMap := make(map[string]string)
+	newKindVersionMap := make(map[string]string)
+	newCompositionMap := make(map[string][]string)
+	newClusterScopedKinds := make(map[string]bool)
+	for kind, plural := range KindPluralMap {
+		newKindPluralMap[kind] = plural
+		newKindVersionMap[kind] = kindVersionMap[kind]
+		newCompositionMap[kind] = compositionMap[kind]
+		if clusterScopedKinds[kind] {
+			newClusterScopedKinds[kind] = true
+		}
+	}
+	trackDiscoveredKind(newKindPluralMap, newKindVersionMap, newCompositionMap, newClusterScopedKinds, k)
+	KindPluralMap = newKindPluralMap
+	kindVersionMap = newKindVersionMap
+	compositionMap = newCompositionMap
+	clusterScopedKinds = newClusterScopedKinds
+	return true
+}
+
+func canTrack(kindPluralMap map[string]string, k discoveredKind) bool {
+	for kind, plural := range kindPluralMap {
+		if kind == k.Kind || strings.EqualFold(plural, k.Plural) {
+			return false
+		}
+	}
+	return true
+}
+
+func trackDiscoveredKind(kindPluralMap, kindVersionMap map[string]string, compositionMap map[string][]string, clusterScopedKinds map[string]bool, k discoveredKind) {
+	kindPluralMap[k.Kind] = k.Plural
+	kindVersionMap[k.Kind] = k.Endpoint
+	compositionMap[k.Kind] = []string{}
+	if k.ClusterScoped {
+		clusterScopedKinds[k.Kind] = true
+	}
+}
+
+// DiscoveredKind is a kind that was learned from the audit stream.
+type DiscoveredKind struct {
+	// plural.group of the resource
+	Resource      string
+	Kind          string
+	Plural        string
+	Group         string
+	Endpoint      string
+	ClusterScoped bool
+	// false if the kind or its plural was taken by another kind
+	Tracked bool
+}
+
+// DiscoveredKindList returns the kinds that were learned from the audit
+// stream, by resource.
+func DiscoveredKindList() []DiscoveredKind {
+	KindLock.RLock()
+	defer KindLock.RUnlock()
+	kinds := make([]DiscoveredKind, 0, len(discoveredKinds))
+	for _, key := range sortedKeys(discoveredKinds) {
+		k := discoveredKinds[key]
+		kinds = append(kinds, DiscoveredKind{
+			Resource:      key,
+			Kind:          k.Kind,
+			Plural:        k.Plural,
+			Group:         k.Group,
+			Endpoint:      k.Endpoint,
+			ClusterScoped: k.ClusterScoped,
+			Tracked:       KindPluralMap[k.Kind] == k.Plural,
+		})
+	}
+	return kinds
+}
+
+// DiscoveredKindsString returns the string representation of the kinds
+// that were learned from the audit stream.
+func DiscoveredKindsString(kinds []DiscoveredKind) string {
+	if len(kinds) == 0 {
+		return "No kinds discovered.\n"
+	}
+	var b strings.Builder
+	for _, k := range kinds {
+		tracked := "tracked"
+		if !k.Tracked {
+			tracked = "not tracked, the kind or plural is taken"
+		}
+		fmt.Fprintf(&b, "%s: %s %s (%s)\n", k.Resource, k.Kind, k.Endpoint, tracked)
+	}
+	return b.String()
+}
+
+func sortedKeys(m map[string]discoveredKind) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
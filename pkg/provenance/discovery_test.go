@@ -0,0 +1,55 @@
+package provenance
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+var discoveryEvents = []string{
+	`{"verb":"create","objectRef":{"resource":"moodles","namespace":"default","name":"moodle1","apiGroup":"moodlecontroller.kubeplus","apiVersion":"v1"},"requestObject":{"apiVersion":"moodlecontroller.kubeplus/v1","kind":"Moodle","metadata":{"name":"moodle1"},"spec":{"plugins":["profilecohort"]}},"requestReceivedTimestamp":"2018-08-05T00:16:20.000000Z"}`,
+	`{"verb":"update","objectRef":{"resource":"deployments","namespace":"default","name":"moodle1","apiGroup":"apps","apiVersion":"v1","subresource":"scale"},"requestObject":{"apiVersion":"autoscaling/v1","kind":"Scale","metadata":{"name":"moodle1"},"spec":{"replicas":2}},"requestReceivedTimestamp":"2018-08-05T00:16:21.000000Z"}`,
+	`{"verb":"create","objectRef":{"resource":"leases","namespace":"default","name":"lease1","apiGroup":"coordination.k8s.io","apiVersion":"v1"},"requestObject":{"apiVersion":"coordination.k8s.io/v1","kind":"Lease","metadata":{"name":"lease1"},"spec":{"holderIdentity":"node1"}},"requestReceivedTimestamp":"2018-08-05T00:16:22.000000Z"}`,
+}
+
+// Tests that kinds are learned from the events, that a subresource body
+// does not name the kind of its parent, and that excluded resources are
+// neither discovered nor ingested.
+func TestKindDiscovery(t *testing.T) {
+	restore := restoreKindMaps()
+	discovered := make(chan bool, len(discoveryEvents))
+	EnableKindDiscovery(nil, []string{"leases.coordination.k8s.io"}, func() { discovered <- true })
+	defer func() {
+		discovery = nil
+		discoveredKinds = make(map[string]discoveredKind)
+		restore()
+	}()
+
+	for _, eventJson := range discoveryEvents {
+		var event Event
+		if err := json.Unmarshal([]byte(eventJson), &event); err != nil {
+			t.Fatalf("Could not parse test event: %s", err)
+		}
+		processEvent(&event)
+	}
+	<-discovered
+
+	if got := kindForPlural("moodles"); got != "Moodle" {
+		t.Errorf("Kind of moodles for TestKindDiscovery() was incorrect, got: %s, want: Moodle.\n", got)
+	}
+	if got := kindVersionMap["Moodle"]; got != "apis/moodlecontroller.kubeplus/v1" {
+		t.Errorf("Endpoint of Moodle for TestKindDiscovery() was incorrect, got: %s, want: apis/moodlecontroller.kubeplus/v1.\n", got)
+	}
+	if got := kindForPlural("deployments"); got == "Scale" {
+		t.Errorf("Kind of deployments for TestKindDiscovery() was taken from a scale body.\n")
+	}
+	if got := kindForPlural("leases"); got != "" {
+		t.Errorf("Kind of leases for TestKindDiscovery() was incorrect, got: %s, want: not tracked.\n", got)
+	}
+	if FindProvenanceObject("leases", "default", "lease1") != nil {
+		t.Errorf("Lineage for the excluded lease1 in TestKindDiscovery() was recorded.\n")
+	}
+	want := "moodles.moodlecontroller.kubeplus: Moodle apis/moodlecontroller.kubeplus/v1 (tracked)\n"
+	if got := DiscoveredKindsString(DiscoveredKindList()); got != want {
+		t.Errorf("Discovered kinds for TestKindDiscovery() was incorrect, got: %s, want: %s.\n", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package provenance
+
+import (
+	"fmt"
+)
+
+// ErrorReason tells the API server which status code an Error maps to.
+// The values match the reasons of metav1.Status.
+type ErrorReason string
+
+const (
+	// a version or attribute that the query refers to does not exist, 404
+	ErrorReasonNotFound ErrorReason = "NotFound"
+	// a query parameter can not be parsed, 400
+	ErrorReasonBadRequest ErrorReason = "BadRequest"
+	// the query parses but does not make sense, such as a field without a value, 422
+	ErrorReasonInvalid ErrorReason = "Invalid"
+)
+
+// Error is returned by the lineage queries.
+type Error struct {
+	Reason ErrorReason
+	// the query parameter or attribute the error is about, if any
+	Field   string
+	Value   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newNotFoundError(field, value, format string, args ...interface{}) *Error {
+	return &Error{Reason: ErrorReasonNotFound, Field: field, Value: value, Message: fmt.Sprintf(format, args...)}
+}
+
+func newBadRequestError(field, value, format string, args ...interface{}) *Error {
+	return &Error{Reason: ErrorReasonBadRequest, Field: field, Value: value, Message: fmt.Sprintf(format, args...)}
+}
+
+func newInvalidError(field, value, format string, args ...interface{}) *Error {
+	return &Error{Reason: ErrorReasonInvalid, Field: field, Value: value, Message: fmt.Sprintf(format, args...)}
+}
+
+// ReasonForError returns the reason of err, and "" if it is not an Error.
+func ReasonForError(err error) ErrorReason {
+	if e, ok := err.(*Error); ok {
+		return e.Reason
+	}
+	return ""
+}
+
+// checkVersions returns a NotFound error if start or end is not a version
+// of the lineage, and an Invalid error if end comes before start.
+func (o ObjectLineage) checkVersions(vNumStart, vNumEnd int) error {
+	if _, ok := o[vNumStart]; !ok {
+		return newNotFoundError("start", fmt.Sprint(vNumStart), "Version %d not found", vNumStart)
+	}
+	if _, ok := o[vNumEnd]; !ok {
+		return newNotFoundError("end", fmt.Sprint(vNumEnd), "Version %d not found", vNumEnd)
+	}
+	if vNumEnd < vNumStart {
+		return newInvalidError("end", fmt.Sprint(vNumEnd), "End version %d is before start version %d", vNumEnd, vNumStart)
+	}
+	return nil
+}
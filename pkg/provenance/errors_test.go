@@ -0,0 +1,37 @@
+package provenance
+
+import (
+	"testing"
+)
+
+// Tests that the lineage queries return an error with the reason the API
+// server maps to a status code.
+func TestLineageErrors(t *testing.T) {
+	objLineage, _ := buildLineage()
+
+	tests := []struct {
+		name   string
+		err    error
+		reason ErrorReason
+	}{
+		{"diff of a missing version", second(objLineage.FullDiff(1, 9)), ErrorReasonNotFound},
+		{"diff of a missing attribute", second(objLineage.FieldDiff("missing", 1, 5)), ErrorReasonNotFound},
+		{"diff ending before its start", second(objLineage.FieldDiff("databases", 5, 1)), ErrorReasonInvalid},
+		{"history ending before its start", second(objLineage.SpecHistoryInterval(4, 2)), ErrorReasonInvalid},
+		{"bisect of a malformed field", second(objLineage.Bisect(map[string]string{"fieldx": "databases"})), ErrorReasonBadRequest},
+		{"bisect of a field without value", second(objLineage.Bisect(map[string]string{"field1": "databases"})), ErrorReasonInvalid},
+	}
+	for _, test := range tests {
+		if reason := ReasonForError(test.err); reason != test.reason {
+			t.Errorf("Error for TestLineageErrors() %s was incorrect, got: %s (%v), want: %s.\n", test.name, reason, test.err, test.reason)
+		}
+	}
+
+	if _, err := objLineage.FieldDiff("databases", 1, 5); err != nil {
+		t.Errorf("Error for TestLineageErrors() was incorrect, got: %v, want: none.\n", err)
+	}
+}
+
+func second(_ string, err error) error {
+	return err
+}
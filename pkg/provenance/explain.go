@@ -0,0 +1,183 @@
+package provenance
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TermResult is the outcome of one term of a bisect query for a version.
+type TermResult struct {
+	// the query parameter of the term, e.g. field2 or query.or[1].field
+	Param string
+	// the term as it was checked, with bare keys resolved, e.g.
+	// users.password eq p1
+	Term   string
+	Passed bool
+	// the list element the term was checked against, e.g. users[1], empty
+	// if its path has no list. Terms met jointly are reported for the
+	// element that met all of them, or else for the one that met the most.
+	Element string
+}
+
+// VersionExplanation is why a version did or did not satisfy a bisect query.
+type VersionExplanation struct {
+	Spec    Spec
+	Matched bool
+	// no terms are checked for a deletion, it never matches
+	Terms []TermResult
+}
+
+// BisectExplain checks the query of the fieldN, opN and valueN parameters
+// against every version, and returns the outcome of each of its terms.
+func (o ObjectLineage) BisectExplain(argMap map[string]string) ([]VersionExplanation, error) {
+	node, err := o.compileArgs(argMap)
+	if err != nil {
+		return nil, err
+	}
+	return o.explain(node), nil
+}
+
+// BisectQueryExplain is BisectExplain for the query tree q.
+func (o ObjectLineage) BisectQueryExplain(q *Query) ([]VersionExplanation, error) {
+	node, err := o.compile(q)
+	if err != nil {
+		return nil, err
+	}
+	return o.explain(node), nil
+}
+
+func (o ObjectLineage) explain(node *queryNode) []VersionExplanation {
+	explanations := make([]VersionExplanation, 0)
+	for _, spec := range getSpecsInOrder(o) {
+		explanation := VersionExplanation{Spec: spec}
+		if !spec.Deleted {
+			root := genericSpec(spec)
+			explanation.Matched = node.holds(root)
+			explanation.Terms = node.explain(root)
+		}
+		explanations = append(explanations, explanation)
+	}
+	return explanations
+}
+
+// explain returns the outcome of the terms of n and of its children. For
+// or and not the outcome of a term is not that of the query, Matched is.
+func (n *queryNode) explain(root map[string]interface{}) []TermResult {
+	results := explainMatch(root, resolveBareKeys(root, n.preds), 0, "", "")
+	for _, child := range n.children() {
+		results = append(results, child.explain(root)...)
+	}
+	return results
+}
+
+// explainMatch walks node like matches, and returns the outcome of each of
+// preds, in their order. at is the location of node in the spec, element
+// that of the list element it is in.
+func explainMatch(node interface{}, preds []predicate, depth int, at, element string) []TermResult {
+	results := make([]TermResult, len(preds))
+	var deeper []int
+	for i, p := range preds {
+		if p.op == opNotEqual && len(p.path) > depth {
+			results[i] = p.result(!matches(node, equalOf(p, preds), depth), element)
+			continue
+		}
+		if len(p.path) == depth {
+			results[i] = p.result(p.test(node), element)
+			continue
+		}
+		deeper = append(deeper, i)
+	}
+	if len(deeper) == 0 {
+		return results
+	}
+	if list, ok := node.([]interface{}); ok && len(list) > 0 {
+		group := predicatesAt(preds, deeper)
+		var best []TermResult
+		bestPassed := -1
+		for i, elem := range list {
+			location := fmt.Sprintf("%s[%d]", at, i)
+			tried := explainMatch(elem, group, depth, location, location)
+			if passed := countPassed(tried); passed > bestPassed {
+				best, bestPassed = tried, passed
+			}
+			if bestPassed == len(group) {
+				break
+			}
+		}
+		for k, i := range deeper {
+			results[i] = best[k]
+		}
+		return results
+	}
+	obj, _ := node.(map[string]interface{})
+	byKey := make(map[string][]int)
+	for _, i := range deeper {
+		key := preds[i].path[depth]
+		byKey[key] = append(byKey[key], i)
+	}
+	for key, indexes := range byKey {
+		location := key
+		if at != "" {
+			location = at + "." + key
+		}
+		tried := explainMatch(obj[key], predicatesAt(preds, indexes), depth+1, location, element)
+		for k, i := range indexes {
+			results[i] = tried[k]
+		}
+	}
+	return results
+}
+
+func predicatesAt(preds []predicate, indexes []int) []predicate {
+	picked := make([]predicate, 0, len(indexes))
+	for _, i := range indexes {
+		picked = append(picked, preds[i])
+	}
+	return picked
+}
+
+func countPassed(results []TermResult) int {
+	passed := 0
+	for _, r := range results {
+		if r.Passed {
+			passed++
+		}
+	}
+	return passed
+}
+
+func (p predicate) result(passed bool, element string) TermResult {
+	return TermResult{Param: p.param, Term: p.String(), Passed: passed, Element: element}
+}
+
+func (p predicate) String() string {
+	field := strings.Join(p.path, ".")
+	if p.op == opExists {
+		return field + " exists"
+	}
+	return fmt.Sprintf("%s %s %s", field, p.op, p.value)
+}
+
+// ExplainString returns the string representation of the explanation of
+// a bisect query.
+func ExplainString(explanations []VersionExplanation) string {
+	var b strings.Builder
+	for _, e := range explanations {
+		outcome := "no match"
+		if e.Matched {
+			outcome = "matches"
+		}
+		fmt.Fprintf(&b, "Version %d (%s): %s\n", e.Spec.Version, describeChange(e.Spec), outcome)
+		for _, term := range e.Terms {
+			status := "failed"
+			if term.Passed {
+				status = "passed"
+			}
+			if term.Element != "" {
+				status += " at " + term.Element
+			}
+			fmt.Fprintf(&b, "  %s %s: %s\n", term.Param, term.Term, status)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,83 @@
+package provenance
+
+import (
+	"testing"
+)
+
+// Tests that an explanation gives the outcome of every term for every
+// version, and the list element that was tried for terms met jointly.
+func TestBisectExplain(t *testing.T) {
+	objLineage := buildNestedLineage()
+
+	explanations, err := objLineage.BisectExplain(map[string]string{"field1": "replicas", "op1": "gt", "value1": "3", "field2": "tls.enabled", "value2": "true"})
+	want := "Version 1 (2018-08-05 00:10:00): no match\n" +
+		"  field1 replicas gt 3: failed\n" +
+		"  field2 tls.enabled eq true: failed\n" +
+		"Version 2 (2018-08-05 00:11:00): no match\n" +
+		"  field1 replicas gt 3: failed\n" +
+		"  field2 tls.enabled eq true: failed\n" +
+		"Version 3 (2018-08-05 00:12:00): matches\n" +
+		"  field1 replicas gt 3: passed\n" +
+		"  field2 tls.enabled eq true: passed\n" +
+		"Version 4 (2018-08-05 00:13:00): matches\n" +
+		"  field1 replicas gt 3: passed\n" +
+		"  field2 tls.enabled eq true: passed\n" +
+		"Version 5 (2018-08-05 00:20:00 by alice, deleted): no match\n"
+	if got := ExplainString(explanations); err != nil || got != want {
+		t.Errorf("Explanation for TestBisectExplain() was incorrect, got: %s %v, want: %s.\n", got, err, want)
+	}
+
+	//no element has both, the first one that has one of them is reported
+	explanations, _ = objLineage.BisectExplain(map[string]string{"field1": "username", "value1": "bob", "field2": "password", "value2": "p1"})
+	wantTerms := []TermResult{
+		{Param: "field1", Term: "users.username eq bob", Passed: false, Element: "users[0]"},
+		{Param: "field2", Term: "users.password eq p1", Passed: true, Element: "users[0]"},
+	}
+	if len(explanations) != 5 || explanations[0].Matched || !equalTerms(explanations[0].Terms, wantTerms) {
+		t.Errorf("Terms for TestBisectExplain() of list elements were incorrect, got: %v, want: %v.\n", explanations, wantTerms)
+	}
+	explanations, _ = objLineage.BisectExplain(map[string]string{"field1": "users[username=bob].password", "value1": "p2"})
+	wantTerms = []TermResult{
+		{Param: "field1", Term: "users.username eq bob", Passed: true, Element: "users[1]"},
+		{Param: "field1", Term: "users.password eq p2", Passed: true, Element: "users[1]"},
+	}
+	if !explanations[0].Matched || !equalTerms(explanations[0].Terms, wantTerms) {
+		t.Errorf("Terms for TestBisectExplain() of a selector were incorrect, got: %v, want: %v.\n", explanations[0], wantTerms)
+	}
+
+	//ne holds only if no element equals the value
+	explanations, _ = objLineage.BisectExplain(map[string]string{"field1": "users.username", "op1": "ne", "value1": "bob"})
+	wantTerms = []TermResult{
+		{Param: "field1", Term: "users.username ne bob", Passed: false},
+	}
+	if explanations[0].Matched || !equalTerms(explanations[0].Terms, wantTerms) {
+		t.Errorf("Terms for TestBisectExplain() of ne on a list were incorrect, got: %v, want: %v.\n", explanations[0], wantTerms)
+	}
+
+	//the terms of a tree are reported where they are in the tree
+	q, _ := ParseQuery([]byte(`{"or": [{"field": "replicas", "value": 1}, {"not": {"field": "image", "value": "postgres:9.6"}}]}`))
+	explanations, err = objLineage.BisectQueryExplain(q)
+	wantTerms = []TermResult{
+		{Param: "query.or[0].field", Term: "replicas eq 1", Passed: false},
+		{Param: "query.or[1].not.field", Term: "image eq postgres:9.6", Passed: false},
+	}
+	if err != nil || !explanations[1].Matched || !equalTerms(explanations[1].Terms, wantTerms) {
+		t.Errorf("Terms for TestBisectExplain() of a query tree were incorrect, got: %v %v, want: %v.\n", explanations[1], err, wantTerms)
+	}
+
+	if _, err := objLineage.BisectExplain(map[string]string{"field1": "replicaz", "value1": "3"}); ReasonForError(err) != ErrorReasonInvalid {
+		t.Errorf("Error for TestBisectExplain() was incorrect, got: %v, want: %s.\n", err, ErrorReasonInvalid)
+	}
+}
+
+func equalTerms(got, want []TermResult) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+package provenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// FieldValue is a run of consecutive versions over which a field had the
+// same value.
+type FieldValue struct {
+	// the version that set the value
+	Start Spec
+	// the last version of the run
+	Last int
+	// false if the field was not set, or the object was deleted
+	Set   bool
+	Value interface{}
+}
+
+// FieldHistory returns the values of field across all versions, a run of
+// versions with the same value is one entry. field is a path like those
+// of bisect, e.g. tls.enabled or users[username=daniel].password. A path
+// through a list without a selector has the values of all its elements.
+func (o ObjectLineage) FieldHistory(field string) ([]FieldValue, error) {
+	path, selectors, err := parsePath("field", field)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]FieldValue, 0)
+	found := false
+	for _, spec := range getSpecsInOrder(o) {
+		var value interface{}
+		set := false
+		if !spec.Deleted {
+			value, set = fieldValue(genericSpec(spec), path, selectors, 0)
+		}
+		found = found || set
+		if n := len(values); n > 0 {
+			last := &values[n-1]
+			if last.Set == set && last.Start.Deleted == spec.Deleted && reflect.DeepEqual(last.Value, value) {
+				last.Last = spec.Version
+				continue
+			}
+		}
+		values = append(values, FieldValue{Start: spec, Last: spec.Version, Set: set, Value: value})
+	}
+	if !found {
+		return nil, newNotFoundError("field", field, "Attribute %s not found in any version", field)
+	}
+	return values, nil
+}
+
+// fieldValue returns the value at path in node, which is at depth of it.
+// A list whose elements are stepped into gives the list of their values,
+// or the value of the one element its selector picks.
+func fieldValue(node interface{}, path []string, selectors []predicate, depth int) (interface{}, bool) {
+	if depth == len(path) {
+		return node, node != nil
+	}
+	if list, ok := node.([]interface{}); ok {
+		selector := false
+		for _, s := range selectors {
+			selector = selector || len(s.path) == depth+1
+		}
+		values := make([]interface{}, 0)
+		for _, elem := range list {
+			if !selectedElement(elem, selectors, depth) {
+				continue
+			}
+			if value, ok := fieldValue(elem, path, selectors, depth); ok {
+				values = append(values, value)
+			}
+		}
+		if len(values) == 0 {
+			return nil, false
+		}
+		if selector && len(values) == 1 {
+			return values[0], true
+		}
+		return values, true
+	}
+	obj, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return fieldValue(obj[path[depth]], path, selectors, depth+1)
+}
+
+// selectedElement reports whether elem, an element of the list at depth,
+// meets the selectors of that list.
+func selectedElement(elem interface{}, selectors []predicate, depth int) bool {
+	for _, s := range selectors {
+		if len(s.path) != depth+1 {
+			continue
+		}
+		obj, _ := elem.(map[string]interface{})
+		if !s.test(obj[s.path[depth]]) {
+			return false
+		}
+	}
+	return true
+}
+
+// FieldHistoryString returns the string representation of the values of
+// field.
+func FieldHistoryString(field string, values []FieldValue) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Values of %s:\n", field)
+	for _, v := range values {
+		versions := fmt.Sprintf("Version %d", v.Start.Version)
+		if v.Last != v.Start.Version {
+			versions = fmt.Sprintf("Versions %d-%d", v.Start.Version, v.Last)
+		}
+		if v.Start.Deleted {
+			fmt.Fprintf(&b, "  %s (%s)\n", versions, describeChange(v.Start))
+			continue
+		}
+		value := "not set"
+		if v.Set {
+			value = fieldValueString(v.Value)
+		}
+		fmt.Fprintf(&b, "  %s (%s): %s\n", versions, describeChange(v.Start), value)
+	}
+	return b.String()
+}
+
+func fieldValueString(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(bytes)
+}
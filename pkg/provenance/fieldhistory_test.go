@@ -0,0 +1,52 @@
+package provenance
+
+import (
+	"testing"
+)
+
+// Tests that the values of a field are listed once per run of versions
+// over which they were unchanged.
+func TestFieldHistory(t *testing.T) {
+	objLineage := buildNestedLineage()
+
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"replicas", "Values of replicas:\n" +
+			"  Version 1 (2018-08-05 00:10:00): 1\n" +
+			"  Version 2 (2018-08-05 00:11:00): 3\n" +
+			"  Versions 3-4 (2018-08-05 00:12:00): 5\n" +
+			"  Version 5 (2018-08-05 00:20:00 by alice, deleted)\n"},
+		{"spec.users[username=daniel].password", "Values of spec.users[username=daniel].password:\n" +
+			"  Versions 1-3 (2018-08-05 00:10:00): p1\n" +
+			"  Version 4 (2018-08-05 00:13:00): p3\n" +
+			"  Version 5 (2018-08-05 00:20:00 by alice, deleted)\n"},
+		{"users.username", "Values of users.username:\n" +
+			"  Versions 1-4 (2018-08-05 00:10:00): [\"daniel\",\"bob\"]\n" +
+			"  Version 5 (2018-08-05 00:20:00 by alice, deleted)\n"},
+		{"tls", "Values of tls:\n" +
+			"  Versions 1-2 (2018-08-05 00:10:00): {\"enabled\":false}\n" +
+			"  Versions 3-4 (2018-08-05 00:12:00): {\"enabled\":true}\n" +
+			"  Version 5 (2018-08-05 00:20:00 by alice, deleted)\n"},
+	}
+	for _, test := range tests {
+		values, err := objLineage.FieldHistory(test.field)
+		if got := FieldHistoryString(test.field, values); err != nil || got != test.want {
+			t.Errorf("Values for TestFieldHistory() %s were incorrect, got: %s %v, want: %s.\n", test.field, got, err, test.want)
+		}
+	}
+
+	//the field is only set from version 3 on
+	values, err := buildLongLineage(5, 3).FieldHistory("users[username=late].password")
+	if err != nil || len(values) != 2 || values[0].Set || values[0].Last != 2 || !values[1].Set || values[1].Value != "p2" || values[1].Last != 5 {
+		t.Errorf("Values for TestFieldHistory() of a field that was added were incorrect, got: %v %v.\n", values, err)
+	}
+
+	if _, err := objLineage.FieldHistory("replicaz"); ReasonForError(err) != ErrorReasonNotFound {
+		t.Errorf("Error for TestFieldHistory() was incorrect, got: %v, want: %s.\n", err, ErrorReasonNotFound)
+	}
+	if _, err := objLineage.FieldHistory("users[username"); ReasonForError(err) != ErrorReasonBadRequest {
+		t.Errorf("Error for TestFieldHistory() was incorrect, got: %v, want: %s.\n", err, ErrorReasonBadRequest)
+	}
+}
@@ -0,0 +1,180 @@
+package provenance
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// What the collector has been doing, for the health checks
+type collectorState struct {
+	running bool
+	started time.Time
+	// the panic that stopped Run, if any
+	panicked    string
+	lastRead    time.Time
+	lastSuccess time.Time
+	// the sample log is read once, after that no reads are expected
+	done              bool
+	errors            int
+	consecutiveErrors int
+	lastError         string
+}
+
+// CollectorStatus is a snapshot of the state of a collector.
+type CollectorStatus struct {
+	Running  bool
+	Started  time.Time
+	Panicked string
+	LogPath  string
+	Offset   int64
+	// -1 if the log could not be read
+	FileSize          int64
+	LastRead          time.Time
+	LastSuccess       time.Time
+	Done              bool
+	Errors            int
+	ConsecutiveErrors int
+	LastError         string
+}
+
+func (c *Collector) setRunning() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.state.running = true
+	c.state.started = time.Now()
+	c.state.panicked = ""
+}
+
+// recordStop marks the collector as stopped. A panic in Run is recovered
+// here, so it shows up in the health checks instead of being lost with
+// the goroutine.
+func (c *Collector) recordStop() {
+	r := recover()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.state.running = false
+	if r != nil {
+		c.state.panicked = fmt.Sprint(r)
+		fmt.Printf("Collector for %s panicked: %v\n", c.LogPath, r)
+	}
+}
+
+func (c *Collector) recordRead(err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := time.Now()
+	c.state.lastRead = now
+	if err != nil {
+		c.state.errors++
+		c.state.consecutiveErrors++
+		c.state.lastError = err.Error()
+		return
+	}
+	c.state.lastSuccess = now
+	c.state.consecutiveErrors = 0
+	c.state.done = c.Once
+}
+
+// Status returns the state of the collector and how far it is behind the
+// end of its log.
+func (c *Collector) Status() CollectorStatus {
+	c.lock.Lock()
+	status := CollectorStatus{
+		Running:           c.state.running,
+		Started:           c.state.started,
+		Panicked:          c.state.panicked,
+		LogPath:           c.LogPath,
+		Offset:            c.checkpoints[c.LogPath],
+		FileSize:          -1,
+		LastRead:          c.state.lastRead,
+		LastSuccess:       c.state.lastSuccess,
+		Done:              c.state.done,
+		Errors:            c.state.errors,
+		ConsecutiveErrors: c.state.consecutiveErrors,
+		LastError:         c.state.lastError,
+	}
+	c.lock.Unlock()
+	if info, err := os.Stat(c.LogPath); err == nil {
+		status.FileSize = info.Size()
+	}
+	return status
+}
+
+// Lag is the number of bytes of the log that are not read yet.
+func (s CollectorStatus) Lag() int64 {
+	if s.FileSize < s.Offset {
+		// the log was rotated, the next read starts from the beginning
+		return s.FileSize
+	}
+	return s.FileSize - s.Offset
+}
+
+// CheckRunning fails when the collector goroutine is not running, because
+// it was never started or it panicked.
+func (c *Collector) CheckRunning() error {
+	s := c.Status()
+	if s.Panicked != "" {
+		return fmt.Errorf("collector panicked: %s", s.Panicked)
+	}
+	if !s.Running {
+		return fmt.Errorf("collector is not running")
+	}
+	return nil
+}
+
+// CheckIngestion fails when the collector is not running, or when the log
+// has not been read successfully for longer than threshold.
+func (c *Collector) CheckIngestion(threshold time.Duration) error {
+	if err := c.CheckRunning(); err != nil {
+		return err
+	}
+	s := c.Status()
+	if s.Done {
+		return nil
+	}
+	lastSuccess := s.LastSuccess
+	if lastSuccess.IsZero() {
+		lastSuccess = s.Started
+	}
+	if stalled := time.Since(lastSuccess); stalled > threshold {
+		return fmt.Errorf("ingestion stalled for %s, %d consecutive errors, last error: %s",
+			stalled.Round(time.Second), s.ConsecutiveErrors, s.LastError)
+	}
+	return nil
+}
+
+// String returns the string representation of the collector status.
+func (s CollectorStatus) String() string {
+	var b strings.Builder
+	state := "running"
+	switch {
+	case s.Panicked != "":
+		state = "stopped after a panic: " + s.Panicked
+	case !s.Running:
+		state = "stopped"
+	case s.Done:
+		state = "running, done reading the sample log"
+	}
+	fmt.Fprintf(&b, "Collector: %s\n", state)
+	if s.FileSize < 0 {
+		fmt.Fprintf(&b, "Log: %s, offset %d, the log can not be read\n", s.LogPath, s.Offset)
+	} else {
+		fmt.Fprintf(&b, "Log: %s, offset %d of %d bytes, lag %d bytes\n", s.LogPath, s.Offset, s.FileSize, s.Lag())
+	}
+	fmt.Fprintf(&b, "Last read: %s\n", formatStatusTime(s.LastRead))
+	fmt.Fprintf(&b, "Last successful read: %s\n", formatStatusTime(s.LastSuccess))
+	fmt.Fprintf(&b, "Errors: %d total, %d consecutive\n", s.Errors, s.ConsecutiveErrors)
+	if s.LastError != "" {
+		fmt.Fprintf(&b, "Last error: %s\n", s.LastError)
+	}
+	return b.String()
+}
+
+func formatStatusTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,52 @@
+package provenance
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tests that the ingestion check fails while the log is missing and passes
+// once it is read, and that a stopped collector fails both checks.
+func TestCollectorHealth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "health")
+	if err != nil {
+		t.Fatalf("Could not create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	logPath := filepath.Join(dir, "audit.log")
+
+	c := NewCollector(logPath, 10*time.Millisecond, false)
+	if err := c.CheckRunning(); err == nil {
+		t.Errorf("CheckRunning() for TestCollectorHealth() passed before the collector was started.\n")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+	if err := c.CheckIngestion(20 * time.Millisecond); err == nil || !strings.Contains(err.Error(), "ingestion stalled") {
+		t.Errorf("CheckIngestion() for TestCollectorHealth() was incorrect, got: %v, want: ingestion stalled.\n", err)
+	}
+
+	appendToLog(t, logPath, collectorTestEvent("9.3"))
+	for i := 0; i < 100 && c.Status().Lag() != 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := c.CheckIngestion(time.Second); err != nil {
+		t.Errorf("CheckIngestion() for TestCollectorHealth() failed with a readable log: %s\n", err)
+	}
+	status := c.Status().String()
+	if !strings.Contains(status, "Collector: running") || !strings.Contains(status, "lag 0 bytes") {
+		t.Errorf("Status for TestCollectorHealth() was incorrect, got: %s\n", status)
+	}
+
+	cancel()
+	c.Wait()
+	if err := c.CheckIngestion(time.Second); err == nil {
+		t.Errorf("CheckIngestion() for TestCollectorHealth() passed after the collector stopped.\n")
+	}
+}
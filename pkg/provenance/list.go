@@ -0,0 +1,95 @@
+package provenance
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ObjectSummary sums up the lineage of an object, for the list of a kind.
+type ObjectSummary struct {
+	Versions    int
+	FirstChange string
+	LastChange  string
+	LastActor   string
+	Deleted     bool
+	// labels of the last version that was not a deletion
+	Labels map[string]string
+}
+
+// Summary returns the summary of the spec lineage of the object.
+func (p *ProvenanceOfObject) Summary() ObjectSummary {
+	summary := ObjectSummary{Versions: len(p.ObjectFullHistory)}
+	specs := getSpecsInOrder(p.ObjectFullHistory)
+	if len(specs) == 0 {
+		return summary
+	}
+	first, last := specs[0], specs[len(specs)-1]
+	summary.FirstChange = first.Timestamp
+	summary.LastChange = last.Timestamp
+	summary.LastActor = last.Actor
+	summary.Deleted = last.Deleted
+	for i := len(specs) - 1; i >= 0; i-- {
+		if !specs[i].Deleted {
+			summary.Labels = specs[i].Labels
+			break
+		}
+	}
+	return summary
+}
+
+func (s ObjectSummary) String() string {
+	str := fmt.Sprintf("%d versions, first change %s, last change %s", s.Versions, s.FirstChange, s.LastChange)
+	if s.LastActor != "" {
+		str = str + " by " + s.LastActor
+	}
+	if s.Deleted {
+		str = str + " (deleted)"
+	}
+	return str
+}
+
+// ListProvenanceObjects returns the tracked objects of resourcePlural in
+// namespace, or in all namespaces if namespace is empty, sorted by namespace
+// and name. Only objects whose name starts with namePrefix and whose labels
+// match selector are listed. The caller holds StoreLock.
+func ListProvenanceObjects(resourcePlural, namespace, namePrefix string, selector labels.Selector) []*ProvenanceOfObject {
+	objects := make([]*ProvenanceOfObject, 0)
+	for _, provObj := range AllProvenanceObjects {
+		if provObj.ResourcePlural != resourcePlural || (namespace != "" && provObj.Namespace != namespace) {
+			continue
+		}
+		if !strings.HasPrefix(provObj.Name, namePrefix) {
+			continue
+		}
+		if !selector.Matches(labels.Set(provObj.Summary().Labels)) {
+			continue
+		}
+		objects = append(objects, provObj)
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].Namespace != objects[j].Namespace {
+			return objects[i].Namespace < objects[j].Namespace
+		}
+		return objects[i].Name < objects[j].Name
+	})
+	return objects
+}
+
+// Returns the string representation of a list of objects.
+func ObjectListString(objects []*ProvenanceOfObject) string {
+	if len(objects) == 0 {
+		return "No objects found.\n"
+	}
+	var b strings.Builder
+	for _, provObj := range objects {
+		name := provObj.Name
+		if provObj.Namespace != "" {
+			name = provObj.Namespace + "/" + name
+		}
+		fmt.Fprintf(&b, "%s: %s\n", name, provObj.Summary())
+	}
+	return b.String()
+}
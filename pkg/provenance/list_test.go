@@ -0,0 +1,51 @@
+package provenance
+
+import (
+	"encoding/json"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var listEvents = []string{
+	`{"verb":"create","user":{"username":"alice"},"objectRef":{"resource":"moodles","namespace":"list-a"},"requestObject":{"apiVersion":"moodlecontroller.kubeplus/v1","kind":"Moodle","metadata":{"name":"web-1","labels":{"tier":"front"}},"spec":{"plugins":["profilecohort"]}},"requestReceivedTimestamp":"2018-08-05T00:16:20.000000Z"}`,
+	`{"verb":"update","user":{"username":"bob"},"objectRef":{"resource":"moodles","namespace":"list-a","name":"web-1"},"requestObject":{"apiVersion":"moodlecontroller.kubeplus/v1","kind":"Moodle","metadata":{"name":"web-1","labels":{"tier":"front"}},"spec":{"plugins":["wiris"]}},"requestReceivedTimestamp":"2018-08-05T00:17:20.000000Z"}`,
+	`{"verb":"create","user":{"username":"alice"},"objectRef":{"resource":"moodles","namespace":"list-a"},"requestObject":{"apiVersion":"moodlecontroller.kubeplus/v1","kind":"Moodle","metadata":{"name":"db-1","labels":{"tier":"back"}},"spec":{"plugins":["profilecohort"]}},"requestReceivedTimestamp":"2018-08-05T00:18:20.000000Z"}`,
+	`{"verb":"create","user":{"username":"alice"},"objectRef":{"resource":"moodles","namespace":"list-b"},"requestObject":{"apiVersion":"moodlecontroller.kubeplus/v1","kind":"Moodle","metadata":{"name":"web-2","labels":{"tier":"front"}},"spec":{"plugins":["profilecohort"]}},"requestReceivedTimestamp":"2018-08-05T00:19:20.000000Z"}`,
+	`{"verb":"delete","user":{"username":"carol"},"objectRef":{"resource":"moodles","namespace":"list-b","name":"web-2"},"requestReceivedTimestamp":"2018-08-05T00:20:20.000000Z"}`,
+}
+
+// Tests that the objects of a kind are listed by namespace, name prefix and
+// label selector, and that a deleted object keeps its last labels.
+func TestListProvenanceObjects(t *testing.T) {
+	for _, eventJson := range listEvents {
+		var event Event
+		if err := json.Unmarshal([]byte(eventJson), &event); err != nil {
+			t.Fatalf("Could not parse test event: %s", err)
+		}
+		processEvent(&event)
+	}
+	front, err := labels.Parse("tier=front")
+	if err != nil {
+		t.Fatalf("Could not parse the label selector: %s", err)
+	}
+
+	tests := []struct {
+		namespace  string
+		namePrefix string
+		selector   labels.Selector
+		want       string
+	}{
+		{"list-a", "", labels.Everything(), "list-a/db-1: 1 versions, first change 2018-08-05 00:18:20, last change 2018-08-05 00:18:20 by alice\n" +
+			"list-a/web-1: 2 versions, first change 2018-08-05 00:16:20, last change 2018-08-05 00:17:20 by bob\n"},
+		{"", "web", front, "list-a/web-1: 2 versions, first change 2018-08-05 00:16:20, last change 2018-08-05 00:17:20 by bob\n" +
+			"list-b/web-2: 2 versions, first change 2018-08-05 00:19:20, last change 2018-08-05 00:20:20 by carol (deleted)\n"},
+		{"list-b", "db", labels.Everything(), "No objects found.\n"},
+	}
+	for _, test := range tests {
+		got := ObjectListString(ListProvenanceObjects("moodles", test.namespace, test.namePrefix, test.selector))
+		if got != test.want {
+			t.Errorf("List of %q for TestListProvenanceObjects() was incorrect, got: %s, want: %s.\n", test.namespace, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package provenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// One entry of metadata.managedFields, as written by server-side apply
+type managedFieldsEntry struct {
+	Manager   string                 `json:"manager"`
+	Operation string                 `json:"operation"`
+	Time      string                 `json:"time"`
+	FieldsV1  map[string]interface{} `json:"fieldsV1"`
+}
+
+// recordFieldManagers stores the user of the event and the field manager
+// of every top level attribute that changed in version.
+func recordFieldManagers(lineage ObjectLineage, version int, event *Event) {
+	spec, ok := lineage[version]
+	if !ok {
+		return
+	}
+	spec.Actor = event.User.Username
+	spec.FieldManagers = make(map[string]string)
+
+	requestManager := requestFieldManager(event)
+	entries := managedFieldsOf(event)
+	for _, path := range changedPaths(lineage[version-1], spec, listKeysOf(entries)) {
+		spec.FieldManagers[path] = ownerOf(path, entries, requestManager)
+	}
+	lineage[version] = spec
+}
+
+// The manager that made the request. Server-side apply sends it as the
+// fieldManager query parameter, otherwise the apiserver defaults it to the
+// name of the client in the user agent.
+func requestFieldManager(event *Event) string {
+	if u, err := url.Parse(event.RequestURI); err == nil {
+		if manager := u.Query().Get("fieldManager"); manager != "" {
+			return manager
+		}
+	}
+	return strings.Split(event.UserAgent, "/")[0]
+}
+
+// managedFields are only complete in the response object, the request
+// object of an update carries whatever the client last read.
+func managedFieldsOf(event *Event) []managedFieldsEntry {
+	if event.ResponseObject != nil {
+		if entries := parseManagedFields(event.ResponseObject.Raw); len(entries) > 0 {
+			return entries
+		}
+	}
+	if event.RequestObject != nil {
+		return parseManagedFields(event.RequestObject.Raw)
+	}
+	return nil
+}
+
+func parseManagedFields(objBytes []byte) []managedFieldsEntry {
+	var obj struct {
+		Metadata struct {
+			ManagedFields []managedFieldsEntry `json:"managedFields"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(objBytes, &obj); err != nil {
+		return nil
+	}
+	return obj.Metadata.ManagedFields
+}
+
+// Spec field paths in the fieldsV1 set of the entry, e.g. tls.enabled or
+// users[username=daniel].password, with the maps and lists that lead to
+// them. A path is true if nothing below it is listed, the entry owns all
+// of it.
+func (e managedFieldsEntry) fieldPaths() map[string]bool {
+	paths := make(map[string]bool)
+	spec, _ := e.FieldsV1["f:spec"].(map[string]interface{})
+	walkFieldsV1(spec, "", paths, nil)
+	return paths
+}
+
+// walkFieldsV1 adds the paths below set, which is at path, to paths, and
+// the key fields of the lists it finds to keys if that is not nil.
+func walkFieldsV1(set map[string]interface{}, path string, paths map[string]bool, keys map[string][]string) {
+	for name, value := range set {
+		var child string
+		switch {
+		case strings.HasPrefix(name, "f:"):
+			child = joinPath(path, name[2:])
+		case strings.HasPrefix(name, "k:"):
+			var key map[string]interface{}
+			if err := json.Unmarshal([]byte(name[2:]), &key); err != nil || len(key) == 0 {
+				continue
+			}
+			if keys != nil {
+				keys[path] = keyNames(key)
+			}
+			child = path + elementSelector(key, keyNames(key))
+		default:
+			//the set itself, or elements of a set or an atomic list
+			continue
+		}
+		below, _ := value.(map[string]interface{})
+		paths[child] = len(below) == 0
+		walkFieldsV1(below, child, paths, keys)
+	}
+}
+
+// listKeysOf returns the key fields of the lists of the spec, by path, as
+// far as the entries show them.
+func listKeysOf(entries []managedFieldsEntry) map[string][]string {
+	keys := make(map[string][]string)
+	for _, entry := range entries {
+		spec, _ := entry.FieldsV1["f:spec"].(map[string]interface{})
+		walkFieldsV1(spec, "", make(map[string]bool), keys)
+	}
+	return keys
+}
+
+// ownsPath reports whether path, or a field the entry owns all of that
+// contains it, is in the fieldsV1 set of the entry.
+func (e managedFieldsEntry) ownsPath(path string) bool {
+	paths := e.fieldPaths()
+	if _, ok := paths[path]; ok {
+		return true
+	}
+	for i := range path {
+		if (path[i] == '.' || path[i] == '[') && paths[path[:i]] {
+			return true
+		}
+	}
+	return false
+}
+
+// The request's own manager wins when it owns the field. When it does
+// not, the field is shared or was taken over, so the most recent owner
+// is reported.
+func ownerOf(path string, entries []managedFieldsEntry, requestManager string) string {
+	owner := ""
+	ownerTime := ""
+	for _, entry := range entries {
+		if !entry.ownsPath(path) {
+			continue
+		}
+		if entry.Manager == requestManager {
+			return requestManager
+		}
+		if owner == "" || entry.Time > ownerTime {
+			owner = entry.Manager
+			ownerTime = entry.Time
+		}
+	}
+	if owner == "" {
+		return requestManager
+	}
+	return owner
+}
+
+// Field paths that were added, removed or changed between two versions,
+// in sorted order. prev is the zero Spec for the first version. The
+// elements of a list are told apart by the key fields in keys, a list
+// without keys is one field.
+func changedPaths(prev, next Spec, keys map[string][]string) []string {
+	changed := make([]string, 0)
+	addChangedPaths(genericSpec(prev), genericSpec(next), "", keys, &changed)
+	sort.Strings(changed)
+	return changed
+}
+
+func addChangedPaths(prev, next interface{}, path string, keys map[string][]string, changed *[]string) {
+	if reflect.DeepEqual(prev, next) {
+		return
+	}
+	prevObj, prevIsMap := prev.(map[string]interface{})
+	nextObj, nextIsMap := next.(map[string]interface{})
+	if (prevIsMap || prev == nil) && (nextIsMap || next == nil) {
+		for name, value := range nextObj {
+			addChangedPaths(prevObj[name], value, joinPath(path, name), keys, changed)
+		}
+		for name, value := range prevObj {
+			if _, ok := nextObj[name]; !ok {
+				addChangedPaths(value, nil, joinPath(path, name), keys, changed)
+			}
+		}
+		return
+	}
+	prevList, prevIsList := prev.([]interface{})
+	nextList, nextIsList := next.([]interface{})
+	prevElems, prevKeyed := keyedElements(prevList, path, keys[path])
+	nextElems, nextKeyed := keyedElements(nextList, path, keys[path])
+	if prevIsList && nextIsList && prevKeyed && nextKeyed {
+		for selector, elem := range nextElems {
+			addChangedPaths(prevElems[selector], elem, selector, keys, changed)
+		}
+		for selector, elem := range prevElems {
+			if _, ok := nextElems[selector]; !ok {
+				addChangedPaths(elem, nil, selector, keys, changed)
+			}
+		}
+		return
+	}
+	*changed = append(*changed, path)
+}
+
+// keyedElements returns the elements of list, the list at path, by their
+// path, if keys identify every one of them.
+func keyedElements(list []interface{}, path string, keys []string) (map[string]interface{}, bool) {
+	elems := make(map[string]interface{})
+	if len(keys) == 0 {
+		return elems, false
+	}
+	for _, elem := range list {
+		obj, ok := elem.(map[string]interface{})
+		if !ok {
+			return elems, false
+		}
+		for _, key := range keys {
+			if _, ok := obj[key]; !ok {
+				return elems, false
+			}
+		}
+		elems[path+elementSelector(obj, keys)] = elem
+	}
+	return elems, true
+}
+
+// What a server-side apply configuration is merged with: the key fields of
+// the lists of the spec, by path, and the managedFields entries of the
+// other field managers.
+type appliedConfig struct {
+	keys   map[string][]string
+	others []managedFieldsEntry
+}
+
+func newAppliedConfig(entries []managedFieldsEntry, requestManager string) *appliedConfig {
+	config := &appliedConfig{keys: listKeysOf(entries)}
+	for _, entry := range entries {
+		if entry.Manager != requestManager {
+			config.others = append(config.others, entry)
+		}
+	}
+	return config
+}
+
+// ownedByOthers reports whether a manager other than the applier owns path.
+func (c *appliedConfig) ownedByOthers(path string) bool {
+	for _, entry := range c.others {
+		if entry.ownsPath(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeApplied merges a server-side apply configuration onto the spec of
+// the latest version: maps are merged, the elements of lists with key
+// fields are merged by key, other values are replaced. A null only gives
+// up the field of the applier, it is removed if no other manager owns it
+// and keeps its latest value otherwise. Neither argument is changed.
+func mergeApplied(latest, applied interface{}, path string, config *appliedConfig) interface{} {
+	if appliedObj, ok := applied.(map[string]interface{}); ok {
+		latestObj, _ := latest.(map[string]interface{})
+		merged := make(map[string]interface{})
+		for name, value := range latestObj {
+			merged[name] = value
+		}
+		for name, value := range appliedObj {
+			if value == nil {
+				if !config.ownedByOthers(joinPath(path, name)) {
+					delete(merged, name)
+				}
+				continue
+			}
+			merged[name] = mergeApplied(latestObj[name], value, joinPath(path, name), config)
+		}
+		return merged
+	}
+	keys := config.keys
+	latestList, _ := latest.([]interface{})
+	appliedList, ok := applied.([]interface{})
+	_, latestKeyed := keyedElements(latestList, path, keys[path])
+	_, appliedKeyed := keyedElements(appliedList, path, keys[path])
+	if !ok || !latestKeyed || !appliedKeyed {
+		return applied
+	}
+	merged := append([]interface{}{}, latestList...)
+	positions := make(map[string]int)
+	for i, elem := range merged {
+		positions[elementSelector(elem.(map[string]interface{}), keys[path])] = i
+	}
+	for _, elem := range appliedList {
+		selector := elementSelector(elem.(map[string]interface{}), keys[path])
+		if i, ok := positions[selector]; ok {
+			merged[i] = mergeApplied(merged[i], elem, path+selector, config)
+			continue
+		}
+		merged = append(merged, elem)
+	}
+	return merged
+}
+
+// elementSelector returns the selector of a list element by its key
+// fields, e.g. [username=daniel], or [containerPort=80,protocol=TCP].
+func elementSelector(elem map[string]interface{}, keys []string) string {
+	terms := make([]string, 0, len(keys))
+	for _, key := range keys {
+		terms = append(terms, key+"="+scalarString(elem[key]))
+	}
+	return "[" + strings.Join(terms, ",") + "]"
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func keyNames(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// UnderField reports whether the field path is field, or a field within
+// it, e.g. users[username=daniel].password is within users.
+func UnderField(path, field string) bool {
+	field = strings.TrimPrefix(field, "spec.")
+	return path == field || strings.HasPrefix(path, field+".") || strings.HasPrefix(path, field+"[")
+}
+
+// FieldManagerHistory lists, per field path, the versions that changed it
+// and the field manager that made each change. If field is not empty only
+// the paths within it are listed.
+func (o ObjectLineage) FieldManagerHistory(field string) string {
+	var b strings.Builder
+	history := make(map[string][]string)
+	for _, spec := range getSpecsInOrder(o) {
+		for attribute, manager := range spec.FieldManagers {
+			if field != "" && !UnderField(attribute, field) {
+				continue
+			}
+			change := fmt.Sprintf("  Version %d %s: %s", spec.Version, spec.Timestamp, manager)
+			if spec.Actor != "" {
+				change = change + " (" + spec.Actor + ")"
+			}
+			history[attribute] = append(history[attribute], change)
+		}
+	}
+	if len(history) == 0 {
+		return "No field manager information found.\n"
+	}
+	var attributes []string
+	for attribute := range history {
+		attributes = append(attributes, attribute)
+	}
+	sort.Strings(attributes)
+	for _, attribute := range attributes {
+		fmt.Fprintf(&b, "%s:\n", attribute)
+		for _, change := range history[attribute] {
+			fmt.Fprintln(&b, change)
+		}
+	}
+	return b.String()
+}
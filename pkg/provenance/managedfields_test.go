@@ -0,0 +1,121 @@
+package provenance
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// A create by kubectl, followed by a server-side apply by argocd that
+// changes replicas. The second event carries managedFields in its response.
+var managedFieldsEvents = []string{
+	`{"verb":"create","requestURI":"/apis/postgrescontroller.kubeplus/v1/namespaces/default/postgreses","user":{"username":"system:admin"},"userAgent":"kubectl/v1.12.0 (linux/amd64) kubernetes/f1b6611","objectRef":{"resource":"postgreses","namespace":"default"},"requestObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"fm-client"},"spec":{"image":"postgres:9.3","replicas":1}},"requestReceivedTimestamp":"2018-08-05T00:16:20.176744Z"}`,
+	`{"verb":"patch","requestURI":"/apis/postgrescontroller.kubeplus/v1/namespaces/default/postgreses/fm-client?fieldManager=argocd&force=true","user":{"username":"system:serviceaccount:argocd:argocd-application-controller"},"userAgent":"argocd-application-controller/v1.0","objectRef":{"resource":"postgreses","namespace":"default","name":"fm-client"},"requestObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"fm-client"},"spec":{"image":"postgres:9.3","replicas":3}},"responseObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"fm-client","managedFields":[{"manager":"kubectl","operation":"Update","time":"2018-08-05T00:16:20Z","fieldsV1":{"f:spec":{"f:image":{}}}},{"manager":"argocd","operation":"Apply","time":"2018-08-05T00:20:00Z","fieldsV1":{"f:spec":{"f:replicas":{}}}}]},"spec":{"image":"postgres:9.3","replicas":3}},"requestReceivedTimestamp":"2018-08-05T00:20:00.000000Z"}`,
+}
+
+func TestFieldManagerHistory(t *testing.T) {
+	for _, eventJson := range managedFieldsEvents {
+		var event Event
+		if err := json.Unmarshal([]byte(eventJson), &event); err != nil {
+			t.Fatalf("Could not parse test event: %s", err)
+		}
+		processEvent(&event)
+	}
+	provObj := FindProvenanceObjectByName("fm-client", AllProvenanceObjects)
+	if provObj == nil {
+		t.Fatalf("No provenance recorded for fm-client")
+	}
+
+	output := provObj.ObjectFullHistory.FieldManagerHistory("")
+	expected := `image:
+  Version 1 2018-08-05 00:16:20: kubectl (system:admin)
+replicas:
+  Version 1 2018-08-05 00:16:20: kubectl (system:admin)
+  Version 2 2018-08-05 00:20:00: argocd (system:serviceaccount:argocd:argocd-application-controller)
+`
+	if output != expected {
+		t.Errorf("Field manager output for TestFieldManagerHistory() was incorrect, got: %s, want: %s.\n", output, expected)
+	}
+}
+
+// A create by kubectl, followed by a server-side apply by an operator that
+// only carries the password of one user, and an apply by argocd that only
+// carries replicas.
+var applyEvents = []string{
+	`{"verb":"create","requestURI":"/apis/postgrescontroller.kubeplus/v1/namespaces/default/postgreses","user":{"username":"system:admin"},"userAgent":"kubectl/v1.12.0 (linux/amd64) kubernetes/f1b6611","objectRef":{"resource":"postgreses","namespace":"default"},"requestObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"apply-client"},"spec":{"image":"postgres:9.3","replicas":1,"tls":{"enabled":false},"users":[{"username":"daniel","password":"p1"},{"username":"bob","password":"p2"}]}},"requestReceivedTimestamp":"2018-08-05T00:16:20.176744Z"}`,
+	`{"verb":"patch","requestURI":"/apis/postgrescontroller.kubeplus/v1/namespaces/default/postgreses/apply-client?fieldManager=operator","user":{"username":"system:serviceaccount:default:operator"},"userAgent":"operator/v1.0","objectRef":{"resource":"postgreses","namespace":"default","name":"apply-client"},"requestObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"apply-client"},"spec":{"tls":{"enabled":true},"users":[{"username":"daniel","password":"p3"}]}},"responseObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"apply-client","managedFields":[{"manager":"kubectl","operation":"Update","time":"2018-08-05T00:16:20Z","fieldsV1":{"f:spec":{"f:image":{},"f:replicas":{},"f:users":{"k:{\"username\":\"bob\"}":{".":{},"f:password":{},"f:username":{}}}}}},{"manager":"operator","operation":"Apply","time":"2018-08-05T00:18:00Z","fieldsV1":{"f:spec":{"f:tls":{"f:enabled":{}},"f:users":{"k:{\"username\":\"daniel\"}":{".":{},"f:password":{},"f:username":{}}}}}}]}},"requestReceivedTimestamp":"2018-08-05T00:18:00.000000Z"}`,
+	`{"verb":"patch","requestURI":"/apis/postgrescontroller.kubeplus/v1/namespaces/default/postgreses/apply-client?fieldManager=argocd&force=true","user":{"username":"argocd"},"userAgent":"argocd-application-controller/v1.0","objectRef":{"resource":"postgreses","namespace":"default","name":"apply-client"},"requestObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"apply-client"},"spec":{"replicas":3}},"requestReceivedTimestamp":"2018-08-05T00:20:00.000000Z"}`,
+}
+
+// Tests that an apply configuration is merged onto the latest version, and
+// that field managers are recorded per field path.
+func TestApplyConfiguration(t *testing.T) {
+	for _, eventJson := range applyEvents {
+		var event Event
+		if err := json.Unmarshal([]byte(eventJson), &event); err != nil {
+			t.Fatalf("Could not parse test event: %s", err)
+		}
+		processEvent(&event)
+	}
+	provObj := FindProvenanceObjectByName("apply-client", AllProvenanceObjects)
+	if provObj == nil {
+		t.Fatalf("No provenance recorded for apply-client")
+	}
+
+	diff, err := provObj.ObjectFullHistory.FieldDiff("image", 1, 3)
+	if diff != "" || err != nil {
+		t.Errorf("Diff output for TestApplyConfiguration() was incorrect, got: %s %v, want no diff.\n", diff, err)
+	}
+	values, _ := provObj.ObjectFullHistory.FieldHistory("users[username=bob].password")
+	if got := FieldHistoryString("users[username=bob].password", values); got != "Values of users[username=bob].password:\n  Versions 1-3 (2018-08-05 00:16:20 by system:admin): p2\n" {
+		t.Errorf("Values for TestApplyConfiguration() were incorrect, got: %s.\n", got)
+	}
+
+	output := provObj.ObjectFullHistory.FieldManagerHistory("")
+	expected := `image:
+  Version 1 2018-08-05 00:16:20: kubectl (system:admin)
+replicas:
+  Version 1 2018-08-05 00:16:20: kubectl (system:admin)
+  Version 3 2018-08-05 00:20:00: argocd (argocd)
+tls.enabled:
+  Version 1 2018-08-05 00:16:20: kubectl (system:admin)
+  Version 2 2018-08-05 00:18:00: operator (system:serviceaccount:default:operator)
+users:
+  Version 1 2018-08-05 00:16:20: kubectl (system:admin)
+users[username=daniel].password:
+  Version 2 2018-08-05 00:18:00: operator (system:serviceaccount:default:operator)
+`
+	if output != expected {
+		t.Errorf("Field manager output for TestApplyConfiguration() was incorrect, got: %s, want: %s.\n", output, expected)
+	}
+	if output := provObj.ObjectFullHistory.FieldManagerHistory("users"); !strings.HasPrefix(output, "users:\n") || strings.Contains(output, "tls") {
+		t.Errorf("Field manager output for TestApplyConfiguration() of users was incorrect, got: %s.\n", output)
+	}
+}
+
+// A create by kubectl, followed by an apply by an operator that sets
+// replicas and backup to null. kubectl still owns replicas.
+var applyNullEvents = []string{
+	`{"verb":"create","requestURI":"/apis/postgrescontroller.kubeplus/v1/namespaces/default/postgreses","user":{"username":"system:admin"},"userAgent":"kubectl/v1.12.0 (linux/amd64) kubernetes/f1b6611","objectRef":{"resource":"postgreses","namespace":"default"},"requestObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"null-client"},"spec":{"image":"postgres:9.3","replicas":1,"backup":"daily"}},"requestReceivedTimestamp":"2018-08-05T00:16:20.176744Z"}`,
+	`{"verb":"patch","requestURI":"/apis/postgrescontroller.kubeplus/v1/namespaces/default/postgreses/null-client?fieldManager=operator","user":{"username":"system:serviceaccount:default:operator"},"userAgent":"operator/v1.0","objectRef":{"resource":"postgreses","namespace":"default","name":"null-client"},"requestObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"null-client"},"spec":{"image":"postgres:10.1","replicas":null,"backup":null}},"responseObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"null-client","managedFields":[{"manager":"kubectl","operation":"Update","time":"2018-08-05T00:16:20Z","fieldsV1":{"f:spec":{"f:replicas":{}}}},{"manager":"operator","operation":"Apply","time":"2018-08-05T00:18:00Z","fieldsV1":{"f:spec":{"f:image":{}}}}]}},"requestReceivedTimestamp":"2018-08-05T00:18:00.000000Z"}`,
+}
+
+// Tests that a null in an apply configuration only removes a field that no
+// other field manager owns.
+func TestApplyNull(t *testing.T) {
+	for _, eventJson := range applyNullEvents {
+		var event Event
+		if err := json.Unmarshal([]byte(eventJson), &event); err != nil {
+			t.Fatalf("Could not parse test event: %s", err)
+		}
+		processEvent(&event)
+	}
+	provObj := FindProvenanceObjectByName("null-client", AllProvenanceObjects)
+	if provObj == nil {
+		t.Fatalf("No provenance recorded for null-client")
+	}
+	got, _ := json.Marshal(provObj.ObjectFullHistory[2].RawSpec)
+	if want := `{"image":"postgres:10.1","replicas":1}`; string(got) != want {
+		t.Errorf("Spec for TestApplyNull() was incorrect, got: %s, want: %s.\n", got, want)
+	}
+}
@@ -0,0 +1,80 @@
+package provenance
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Manifest rebuilds the object of version, so it can be applied again:
+// apiVersion, kind, metadata with name, namespace and labels, and the spec
+// as it was sent. Returns a NotFound error if the version does not exist or
+// records a deletion, and an Invalid error if the spec holds a redacted
+// value: applying the manifest would write the marker over the secret.
+func (p *ProvenanceOfObject) Manifest(version int) (map[string]interface{}, error) {
+	manifest, err := p.manifest(version)
+	if err != nil {
+		return nil, err
+	}
+	if field := redactedField(manifest["spec"], "spec"); field != "" {
+		return nil, newInvalidError("version", strconv.Itoa(version),
+			"Field %s is redacted in version %d, its value can not be restored, use rollback with the fields parameter to restore the other fields", field, version)
+	}
+	return manifest, nil
+}
+
+// manifest is Manifest with the redacted values left in, for callers that
+// pick fields of it and check those.
+func (p *ProvenanceOfObject) manifest(version int) (map[string]interface{}, error) {
+	spec, ok := p.ObjectFullHistory[version]
+	if !ok {
+		return nil, newNotFoundError("version", strconv.Itoa(version), "Version %d not found", version)
+	}
+	if spec.Deleted {
+		return nil, newNotFoundError("version", strconv.Itoa(version), "Version %d records the deletion of the object", version)
+	}
+	kind := spec.Kind
+	if kind == "" {
+		kind = kindForPlural(p.ResourcePlural)
+	}
+	apiVersion := spec.APIVersion
+	if apiVersion == "" {
+		apiVersion = apiVersionOfKind(kind)
+	}
+
+	metadata := map[string]interface{}{"name": p.Name}
+	if p.Namespace != "" {
+		metadata["namespace"] = p.Namespace
+	}
+	if len(spec.Labels) > 0 {
+		labels := make(map[string]interface{})
+		for key, value := range spec.Labels {
+			labels[key] = value
+		}
+		metadata["labels"] = labels
+	}
+	manifest := map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   metadata,
+	}
+	switch {
+	case spec.RawSpec != nil:
+		manifest["spec"] = spec.RawSpec
+	case len(spec.AttributeToData) > 0:
+		//a version built without a request object, e.g. in the tests
+		manifest["spec"] = spec.AttributeToData
+	}
+	return manifest, nil
+}
+
+// The apiVersion of kind, from its endpoint in the kind compositions file,
+// e.g. apis/apps/v1 is apps/v1 and api/v1 is v1.
+func apiVersionOfKind(kind string) string {
+	KindLock.RLock()
+	endpoint := kindVersionMap[kind]
+	KindLock.RUnlock()
+	if strings.HasPrefix(endpoint, "apis/") {
+		return strings.TrimPrefix(endpoint, "apis/")
+	}
+	return strings.TrimPrefix(endpoint, "api/")
+}
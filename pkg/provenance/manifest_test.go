@@ -0,0 +1,73 @@
+package provenance
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var manifestEvents = []string{
+	`{"verb":"create","user":{"username":"alice"},"objectRef":{"resource":"moodles","namespace":"manifest-a"},"requestObject":{"apiVersion":"moodlecontroller.kubeplus/v1","kind":"Moodle","metadata":{"name":"lms","labels":{"tier":"front"},"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"apiVersion\":\"moodlecontroller.kubeplus/v1\",\"kind\":\"Moodle\",\"metadata\":{\"name\":\"lms\",\"namespace\":\"manifest-a\",\"labels\":{\"tier\":\"front\"}},\"spec\":{\"plugins\":[\"profilecohort\"],\"domainName\":\"lms.example.com\",\"tls\":{\"enabled\":true}}}"}},"spec":{"plugins":["profilecohort"]}},"requestReceivedTimestamp":"2018-08-05T00:16:20.000000Z"}`,
+	`{"verb":"update","user":{"username":"bob"},"objectRef":{"resource":"moodles","namespace":"manifest-a","name":"lms","subresource":"scale"},"requestObject":{"apiVersion":"autoscaling/v1","kind":"Scale","metadata":{"name":"lms"},"spec":{"replicas":3}},"requestReceivedTimestamp":"2018-08-05T00:17:20.000000Z"}`,
+	`{"verb":"delete","user":{"username":"carol"},"objectRef":{"resource":"moodles","namespace":"manifest-a","name":"lms"},"requestReceivedTimestamp":"2018-08-05T00:18:20.000000Z"}`,
+}
+
+// Tests that a version is rebuilt as an object that can be applied again,
+// with the nested fields of the spec and without server set metadata.
+func TestManifest(t *testing.T) {
+	for _, eventJson := range manifestEvents {
+		var event Event
+		if err := json.Unmarshal([]byte(eventJson), &event); err != nil {
+			t.Fatalf("Could not parse test event: %s", err)
+		}
+		processEvent(&event)
+	}
+	provObj := FindProvenanceObject("moodles", "manifest-a", "lms")
+	if provObj == nil {
+		t.Fatalf("Object for TestManifest() was not recorded.\n")
+	}
+
+	tests := []struct {
+		version int
+		want    string
+	}{
+		{1, `{"apiVersion":"moodlecontroller.kubeplus/v1","kind":"Moodle","metadata":{"labels":{"tier":"front"},"name":"lms","namespace":"manifest-a"},"spec":{"domainName":"lms.example.com","plugins":["profilecohort"],"tls":{"enabled":true}}}`},
+		{2, `{"apiVersion":"moodlecontroller.kubeplus/v1","kind":"Moodle","metadata":{"labels":{"tier":"front"},"name":"lms","namespace":"manifest-a"},"spec":{"domainName":"lms.example.com","plugins":["profilecohort"],"replicas":3,"tls":{"enabled":true}}}`},
+	}
+	for _, test := range tests {
+		manifest, err := provObj.Manifest(test.version)
+		if err != nil {
+			t.Errorf("Error for TestManifest() of version %d was incorrect, got: %v, want: none.\n", test.version, err)
+			continue
+		}
+		got, _ := json.Marshal(manifest)
+		if string(got) != test.want {
+			t.Errorf("Manifest for TestManifest() of version %d was incorrect, got: %s, want: %s.\n", test.version, got, test.want)
+		}
+	}
+
+	for _, version := range []int{3, 4} {
+		if _, err := provObj.Manifest(version); ReasonForError(err) != ErrorReasonNotFound {
+			t.Errorf("Error for TestManifest() of version %d was incorrect, got: %v, want: %s.\n", version, err, ErrorReasonNotFound)
+		}
+	}
+}
+
+// Tests that a version with a redacted value is not served as a manifest,
+// applying it would write the marker over the secret.
+func TestManifestRedacted(t *testing.T) {
+	defer withRedaction("Postgres", redaction{Fields: []string{"spec.users.password"}})()
+
+	provObj := NewProvenanceOfObject()
+	provObj.ResourcePlural = "postgreses"
+	provObj.Name = "client25"
+	parseRequestObject(provObj, postgresRequestObject(`{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","spec":{"image":"postgres:9.6","users":[{"username":"daniel","password":"pass123"}]}}`), "2018-08-05 00:10:00")
+	parseRequestObject(provObj, postgresRequestObject(`{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","spec":{"image":"postgres:10.1"}}`), "2018-08-05 00:11:00")
+
+	if _, err := provObj.Manifest(1); ReasonForError(err) != ErrorReasonInvalid || !strings.Contains(err.Error(), "spec.users.password") {
+		t.Errorf("Error for TestManifestRedacted() was incorrect, got: %v, want: %s for spec.users.password.\n", err, ErrorReasonInvalid)
+	}
+	if _, err := provObj.Manifest(2); err != nil {
+		t.Errorf("Error for TestManifestRedacted() of a version without secrets was incorrect, got: %v, want: none.\n", err)
+	}
+}
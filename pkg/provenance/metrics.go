@@ -0,0 +1,115 @@
+package provenance
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsSubsystem = "kubeprovenance"
+
+var (
+	eventsRead = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: metricsSubsystem,
+			Name:      "audit_events_read_total",
+			Help:      "Number of audit events read from the audit log or received from the webhook.",
+		},
+	)
+	eventsParsed = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: metricsSubsystem,
+			Name:      "audit_events_parsed_total",
+			Help:      "Number of audit events that were parsed and processed, including skipped ones.",
+		},
+	)
+	eventsSkipped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: metricsSubsystem,
+			Name:      "audit_events_skipped_total",
+			Help:      "Number of parsed audit events that did not change any lineage, by reason.",
+		},
+		[]string{"reason"},
+	)
+	eventsFailed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: metricsSubsystem,
+			Name:      "audit_events_failed_total",
+			Help:      "Number of audit events that were rejected or only partially parsed, by reason.",
+		},
+		[]string{"reason"},
+	)
+	versionsCreated = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: metricsSubsystem,
+			Name:      "versions_created_total",
+			Help:      "Number of spec versions created, by resource kind.",
+		},
+		[]string{"kind"},
+	)
+	trackedObjects = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Subsystem: metricsSubsystem,
+			Name:      "tracked_objects",
+			Help:      "Number of objects with a provenance lineage.",
+		},
+		func() float64 {
+			StoreLock.RLock()
+			defer StoreLock.RUnlock()
+			return float64(len(AllProvenanceObjects))
+		},
+	)
+	ingestionLag = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: metricsSubsystem,
+			Name:      "ingestion_lag_seconds",
+			Help:      "Time between the request of the last ingested audit event and its ingestion.",
+		},
+	)
+)
+
+// Reasons for skipped and failed events
+const (
+	reasonFailedRequest   = "failed_request"
+	reasonExcluded        = "excluded"
+	reasonUntracked       = "untracked"
+	reasonNoRequestObject = "no_request_object"
+	reasonNoSpec          = "no_spec"
+	reasonInvalidJSON     = "invalid_json"
+	reasonNoObjectRef     = "no_object_ref"
+	reasonPartial         = "partial"
+	reasonBadSelector     = "bad_selector"
+)
+
+var registerMetrics sync.Once
+
+// RegisterMetrics registers the ingestion metrics with the default
+// prometheus registry, which the generic apiserver serves on /metrics.
+func RegisterMetrics() {
+	registerMetrics.Do(func() {
+		prometheus.MustRegister(eventsRead)
+		prometheus.MustRegister(eventsParsed)
+		prometheus.MustRegister(eventsSkipped)
+		prometheus.MustRegister(eventsFailed)
+		prometheus.MustRegister(versionsCreated)
+		prometheus.MustRegister(trackedObjects)
+		prometheus.MustRegister(ingestionLag)
+	})
+}
+
+// countVersion counts a new version of an object of the resource plural.
+func countVersion(plural string) {
+	kind := kindForPlural(plural)
+	if kind == "" {
+		kind = plural
+	}
+	versionsCreated.WithLabelValues(kind).Inc()
+}
+
+func recordIngestionLag(event *Event) {
+	if event.RequestReceivedTimestamp.IsZero() {
+		return
+	}
+	ingestionLag.Set(time.Since(event.RequestReceivedTimestamp.Time).Seconds())
+}
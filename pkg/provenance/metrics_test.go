@@ -0,0 +1,44 @@
+package provenance
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	var m dto.Metric
+	if err := c.Write(&m); err != nil {
+		t.Fatalf("Could not read metric: %s", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+// Tests that ingestion counts read, failed and skipped events, and the
+// versions it creates.
+func TestIngestionMetrics(t *testing.T) {
+	read := counterValue(t, eventsRead)
+	invalid := counterValue(t, eventsFailed.WithLabelValues(reasonInvalidJSON))
+	failedRequest := counterValue(t, eventsSkipped.WithLabelValues(reasonFailedRequest))
+	versions := counterValue(t, versionsCreated.WithLabelValues("postgreses"))
+
+	ingestEvent([]byte(`{"verb":"create",`), "metrics.log", 0)
+	ingestEvent([]byte(`{"verb":"create","objectRef":{"resource":"postgreses","namespace":"metrics","name":"m1"},"responseStatus":{"code":409},"requestReceivedTimestamp":"2018-08-05T00:16:20.000000Z"}`), "metrics.log", 18)
+	ingestEvent([]byte(`{"verb":"create","objectRef":{"resource":"postgreses","namespace":"metrics","name":"m1"},"requestObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"m1"},"spec":{"image":"postgres:9.3"}},"requestReceivedTimestamp":"2018-08-05T00:16:21.000000Z"}`), "metrics.log", 40)
+
+	for _, c := range []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"read", counterValue(t, eventsRead) - read, 3},
+		{"invalid_json", counterValue(t, eventsFailed.WithLabelValues(reasonInvalidJSON)) - invalid, 1},
+		{"failed_request", counterValue(t, eventsSkipped.WithLabelValues(reasonFailedRequest)) - failedRequest, 1},
+		{"versions", counterValue(t, versionsCreated.WithLabelValues("postgreses")) - versions, 1},
+	} {
+		if c.got != c.want {
+			t.Errorf("Metric %s for TestIngestionMetrics() was incorrect, got: %v, want: %v.\n", c.name, c.got, c.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package provenance
+
+import (
+	"sort"
+)
+
+// BisectVersionMonotonic is BisectVersion for a query that, once it holds,
+// holds at every later version, e.g. that a user exists in a list that is
+// only appended to. The versions are binary searched, so only O(log n) of
+// them are decoded and checked. Deletions are skipped, they never match.
+// If the query is not monotonic the version found is one where it started
+// to hold, not necessarily the first.
+func (o ObjectLineage) BisectVersionMonotonic(argMap map[string]string) (Spec, bool, error) {
+	node, err := argsNode(argMap)
+	if err != nil {
+		return Spec{}, false, err
+	}
+	return o.searchMonotonic(node)
+}
+
+// BisectQueryMonotonic is BisectVersionMonotonic for the query tree q.
+func (o ObjectLineage) BisectQueryMonotonic(q *Query) (Spec, bool, error) {
+	node, err := compileQuery(*q, "query")
+	if err != nil {
+		return Spec{}, false, err
+	}
+	return o.searchMonotonic(node)
+}
+
+func (o ObjectLineage) searchMonotonic(node *queryNode) (Spec, bool, error) {
+	//only the version numbers are sorted, the specs are looked up as they
+	//are probed
+	versions := make([]int, 0, len(o))
+	for version, spec := range o {
+		if !spec.Deleted {
+			versions = append(versions, version)
+		}
+	}
+	sort.Ints(versions)
+	probed := make([]Spec, 0)
+	first := sort.Search(len(versions), func(i int) bool {
+		spec := o[versions[i]]
+		probed = append(probed, spec)
+		return node.holds(genericSpec(spec))
+	})
+	//the fields are checked against the probed versions, all of them are
+	//only decoded when a field is in none of those
+	if node.checkFields(probed) != nil {
+		if err := node.checkFields(getSpecsInOrder(o)); err != nil {
+			return Spec{}, false, err
+		}
+	}
+	if first == len(versions) {
+		return Spec{}, false, nil
+	}
+	return o[versions[first]], true, nil
+}
@@ -0,0 +1,85 @@
+package provenance
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Builds a lineage of n versions whose users list is only appended to,
+// the user late is added at version from.
+func buildLongLineage(n, from int) ObjectLineage {
+	daniel := map[string]interface{}{"username": "daniel", "password": "p1"}
+	late := map[string]interface{}{"username": "late", "password": "p2"}
+	objLineage := ObjectLineage{}
+	for version := 1; version <= n; version++ {
+		users := []interface{}{daniel}
+		if version >= from {
+			users = append(users, late)
+		}
+		objLineage[version] = Spec{
+			Version:   version,
+			Timestamp: fmt.Sprintf("2018-08-05 %02d:%02d:%02d", version/3600%24, version/60%60, version%60),
+			RawSpec:   map[string]interface{}{"replicas": float64(version), "users": users},
+		}
+	}
+	return objLineage
+}
+
+// Tests that the binary search finds the same version as the linear scan
+// for monotonic queries, and validates the query the same way.
+func TestBisectMonotonic(t *testing.T) {
+	objLineage := buildNestedLineage()
+	tests := []map[string]string{
+		{"field1": "databases", "op1": "contains", "value1": "logging"},
+		{"field1": "replicas", "op1": "ge", "value1": "3"},
+		{"field1": "users[username=daniel].password", "op1": "exists"},
+		{"field1": "replicas", "op1": "gt", "value1": "10"},
+	}
+	for _, query := range tests {
+		want, wantFound, _ := objLineage.BisectVersion(query)
+		got, found, err := objLineage.BisectVersionMonotonic(query)
+		if err != nil || found != wantFound || got.Version != want.Version {
+			t.Errorf("Version for TestBisectMonotonic() %v was incorrect, got: %d %t %v, want: %d %t.\n", query, got.Version, found, err, want.Version, wantFound)
+		}
+	}
+
+	long := buildLongLineage(1000, 777)
+	for _, query := range []map[string]string{
+		{"field1": "username", "value1": "late"},
+		{"field1": "replicas", "op1": "ge", "value1": "1"},
+		{"field1": "replicas", "op1": "gt", "value1": "1000"},
+	} {
+		want, wantFound, _ := long.BisectVersion(query)
+		got, found, err := long.BisectVersionMonotonic(query)
+		if err != nil || found != wantFound || got.Version != want.Version {
+			t.Errorf("Version for TestBisectMonotonic() %v was incorrect, got: %d %t %v, want: %d %t.\n", query, got.Version, found, err, want.Version, wantFound)
+		}
+	}
+
+	q, _ := ParseQuery([]byte(`{"or": [{"field": "replicas", "op": "ge", "value": 900}, {"field": "username", "value": "late"}]}`))
+	if spec, found, err := long.BisectQueryMonotonic(q); err != nil || !found || spec.Version != 777 {
+		t.Errorf("Version for TestBisectMonotonic() of a query tree was incorrect, got: %d %t %v, want: 777.\n", spec.Version, found, err)
+	}
+
+	if _, _, err := long.BisectVersionMonotonic(map[string]string{"field1": "usernme", "value1": "late"}); ReasonForError(err) != ErrorReasonInvalid {
+		t.Errorf("Error for TestBisectMonotonic() was incorrect, got: %v, want: %s.\n", err, ErrorReasonInvalid)
+	}
+}
+
+var benchmarkQuery = map[string]string{"field1": "users[username=late].password", "op1": "exists"}
+
+func BenchmarkBisectLinear(b *testing.B) {
+	objLineage := buildLongLineage(20000, 19000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		objLineage.BisectVersion(benchmarkQuery)
+	}
+}
+
+func BenchmarkBisectMonotonic(b *testing.B) {
+	objLineage := buildLongLineage(20000, 19000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		objLineage.BisectVersionMonotonic(benchmarkQuery)
+	}
+}
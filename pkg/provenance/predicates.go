@@ -0,0 +1,286 @@
+package provenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The operators of a bisect predicate, given as opN next to fieldN and
+// valueN. Without opN a field must equal its value.
+const (
+	opEqual        = "eq"
+	opNotEqual     = "ne"
+	opGreater      = "gt"
+	opGreaterEqual = "ge"
+	opLess         = "lt"
+	opLessEqual    = "le"
+	opMatches      = "matches"
+	opContains     = "contains"
+	opExists       = "exists"
+)
+
+// A condition on the values a path of the spec leads to, e.g. replicas gt 3.
+type predicate struct {
+	// the query parameter of the field, for the errors
+	param string
+	// path relative to spec, list elements are stepped through without an
+	// index, like the paths of the redaction rules
+	path  []string
+	op    string
+	value string
+	// the value of a numeric comparison or the regular expression of matches
+	number float64
+	re     *regexp.Regexp
+}
+
+// parsePredicates builds the predicates of field, op and value, the query
+// parameters param, opParam and valueParam. A selector, as in
+// users[username=daniel].password, adds an eq predicate on the same list
+// element.
+func parsePredicates(param, field, opParam, op, valueParam, value string) ([]predicate, error) {
+	if op == "" {
+		op = opEqual
+	}
+	path, selectors, err := parsePath(param, field)
+	if err != nil {
+		return nil, err
+	}
+	p := predicate{param: param, path: path, op: op, value: value}
+	switch op {
+	case opEqual, opNotEqual, opContains, opExists:
+	case opGreater, opGreaterEqual, opLess, opLessEqual:
+		if p.number, err = strconv.ParseFloat(value, 64); err != nil {
+			return nil, newBadRequestError(valueParam, value, "Could not parse %s to a number for operator %s", valueParam, op)
+		}
+	case opMatches:
+		if p.re, err = regexp.Compile(value); err != nil {
+			return nil, newBadRequestError(valueParam, value, "Could not parse %s to a regular expression: %s", valueParam, err)
+		}
+	default:
+		return nil, newBadRequestError(opParam, op, "Unknown operator %s, want one of eq, ne, gt, ge, lt, le, matches, contains or exists", op)
+	}
+	return append(selectors, p), nil
+}
+
+// parsePath splits a path such as spec.users[username=daniel].password at
+// its dots, and returns the selectors as eq predicates.
+func parsePath(param, field string) ([]string, []predicate, error) {
+	var path []string
+	var selectors []predicate
+	rest := strings.TrimPrefix(field, "spec.")
+	for rest != "" {
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		key := rest[:end]
+		if key == "" {
+			return nil, nil, newBadRequestError(param, field, "Could not parse %s, %s has an empty path element", param, field)
+		}
+		path = append(path, key)
+		rest = rest[end:]
+		if strings.HasPrefix(rest, "[") {
+			closing := strings.Index(rest, "]")
+			var selector []string
+			if closing >= 0 {
+				selector = strings.SplitN(rest[1:closing], "=", 2)
+			}
+			if len(selector) != 2 || selector[0] == "" {
+				return nil, nil, newBadRequestError(param, field, "Could not parse %s, a selector of %s is not [key=value]", param, field)
+			}
+			selectorPath := append(append([]string{}, path...), selector[0])
+			selectors = append(selectors, predicate{param: param, path: selectorPath, op: opEqual, value: selector[1]})
+			rest = rest[closing+1:]
+		}
+		if strings.HasPrefix(rest, ".") {
+			rest = rest[1:]
+			if rest == "" {
+				return nil, nil, newBadRequestError(param, field, "Could not parse %s, %s ends with a dot", param, field)
+			}
+		} else if rest != "" {
+			return nil, nil, newBadRequestError(param, field, "Could not parse %s, expected a dot after a selector in %s", param, field)
+		}
+	}
+	if len(path) == 0 {
+		return nil, nil, newBadRequestError(param, field, "Could not parse %s, the field is empty", param)
+	}
+	return path, selectors, nil
+}
+
+// matches reports whether node, the value at depth of the paths of preds,
+// meets all of them. A ne predicate holds if its eq predicate, together
+// with the selectors of its field, does not match. Like ne on a list value,
+// users.username ne daniel holds if no user is daniel, not if one of the
+// users is someone else.
+func matches(node interface{}, preds []predicate, depth int) bool {
+	var deeper []predicate
+	for _, p := range preds {
+		if p.op == opNotEqual && len(p.path) > depth {
+			if matches(node, equalOf(p, preds), depth) {
+				return false
+			}
+			continue
+		}
+		if len(p.path) == depth {
+			if !p.test(node) {
+				return false
+			}
+			continue
+		}
+		deeper = append(deeper, p)
+	}
+	if len(deeper) == 0 {
+		return true
+	}
+	if list, ok := node.([]interface{}); ok && len(list) > 0 {
+		//the elements are not part of the path, one of them must meet all
+		for _, elem := range list {
+			if matches(elem, deeper, depth) {
+				return true
+			}
+		}
+		return false
+	}
+	obj, _ := node.(map[string]interface{})
+	byKey := make(map[string][]predicate)
+	for _, p := range deeper {
+		byKey[p.path[depth]] = append(byKey[p.path[depth]], p)
+	}
+	for key, group := range byKey {
+		if !matches(obj[key], group, depth+1) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalOf returns the eq predicate of the ne predicate p, and the selectors
+// of its field in preds, which have the same param.
+func equalOf(p predicate, preds []predicate) []predicate {
+	var equal []predicate
+	for _, other := range preds {
+		if other.param == p.param && other.op == opEqual {
+			equal = append(equal, other)
+		}
+	}
+	p.op = opEqual
+	return append(equal, p)
+}
+
+// test checks a single value. A list meets a predicate if one of its
+// elements does, ne holds if no element equals the value. A list contains
+// the value if one of its elements equals it, contains is only a substring
+// match for strings.
+func (p predicate) test(value interface{}) bool {
+	switch p.op {
+	case opExists:
+		return value != nil
+	case opNotEqual:
+		return !predicate{op: opEqual, value: p.value}.test(value)
+	}
+	if list, ok := value.([]interface{}); ok {
+		if p.op == opContains {
+			p = predicate{op: opEqual, value: p.value}
+		}
+		for _, elem := range list {
+			if p.test(elem) {
+				return true
+			}
+		}
+		return false
+	}
+	if value == nil {
+		return false
+	}
+	str := scalarString(value)
+	switch p.op {
+	case opEqual:
+		return valuesMatch(str, p.value)
+	case opContains:
+		return strings.Contains(str, p.value)
+	case opMatches:
+		return p.re.MatchString(str)
+	}
+	number, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return false
+	}
+	switch p.op {
+	case opGreater:
+		return number > p.number
+	case opGreaterEqual:
+		return number >= p.number
+	case opLess:
+		return number < p.number
+	case opLessEqual:
+		return number <= p.number
+	}
+	return false
+}
+
+func scalarString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}:
+		bytes, _ := json.Marshal(v)
+		return string(bytes)
+	}
+	return fmt.Sprint(value)
+}
+
+// resolveBareKeys keeps the queries of earlier releases working: a field
+// that is not an attribute of the spec, such as username, is looked up in
+// the elements of the list attributes, users.username.
+func resolveBareKeys(root map[string]interface{}, preds []predicate) []predicate {
+	resolved := make([]predicate, 0, len(preds))
+	for _, p := range preds {
+		if _, ok := root[p.path[0]]; !ok {
+			if attribute := listAttributeWithKey(root, p.path[0]); attribute != "" {
+				p.path = append([]string{attribute}, p.path...)
+			}
+		}
+		resolved = append(resolved, p)
+	}
+	return resolved
+}
+
+func listAttributeWithKey(root map[string]interface{}, key string) string {
+	attributes := make([]string, 0, len(root))
+	for attribute := range root {
+		attributes = append(attributes, attribute)
+	}
+	sort.Strings(attributes)
+	for _, attribute := range attributes {
+		list, _ := root[attribute].([]interface{})
+		for _, elem := range list {
+			if obj, ok := elem.(map[string]interface{}); ok {
+				if _, ok := obj[key]; ok {
+					return attribute
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// The spec as decoded JSON. A version recorded without a request object
+// only has its built attributes, they are decoded the same way.
+func genericSpec(spec Spec) map[string]interface{} {
+	if spec.RawSpec != nil {
+		return spec.RawSpec
+	}
+	bytes, err := json.Marshal(spec.AttributeToData)
+	if err != nil {
+		return nil
+	}
+	var generic map[string]interface{}
+	json.Unmarshal(bytes, &generic)
+	return generic
+}
@@ -0,0 +1,78 @@
+package provenance
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Builds the lineage of a Postgres whose spec has numbers, nested objects
+// and lists of objects, ending with its deletion.
+func buildNestedLineage() ObjectLineage {
+	provObj := NewProvenanceOfObject()
+	provObj.ResourcePlural = "postgreses"
+	provObj.Name = "client25"
+	for i, lastApplied := range []string{
+		`{"kind":"Postgres","spec":{"replicas":1,"image":"postgres:9.6","databases":["a"],"tls":{"enabled":false},"users":[{"username":"daniel","password":"p1"},{"username":"bob","password":"p2"}]}}`,
+		`{"kind":"Postgres","spec":{"replicas":3,"image":"postgres:10.1","databases":["a"],"tls":{"enabled":false},"users":[{"username":"daniel","password":"p1"},{"username":"bob","password":"p2"}]}}`,
+		`{"kind":"Postgres","spec":{"replicas":5,"image":"postgres:10.1","databases":["a","logging"],"tls":{"enabled":true},"users":[{"username":"daniel","password":"p1"},{"username":"bob","password":"p2"}]}}`,
+		`{"kind":"Postgres","spec":{"replicas":5,"image":"postgres:10.1","databases":["a","logging"],"tls":{"enabled":true},"users":[{"username":"daniel","password":"p3"},{"username":"bob","password":"p2"}]}}`,
+	} {
+		parseRequestObject(provObj, postgresRequestObject(lastApplied), fmt.Sprintf("2018-08-05 00:1%d:00", i))
+	}
+	addTombstone(provObj, "alice", "2018-08-05 00:20:00", "")
+	return provObj.ObjectFullHistory
+}
+
+// Tests the operators of bisect predicates and the paths into nested
+// objects and list elements.
+func TestBisectPredicates(t *testing.T) {
+	objLineage := buildNestedLineage()
+
+	tests := []struct {
+		name  string
+		query map[string]string
+		want  string
+	}{
+		{"greater than", map[string]string{"field1": "replicas", "op1": "gt", "value1": "3"}, "Version: 3"},
+		{"greater or equal", map[string]string{"field1": "spec.replicas", "op1": "ge", "value1": "3"}, "Version: 2"},
+		{"less than", map[string]string{"field1": "replicas", "op1": "lt", "value1": "2"}, "Version: 1"},
+		{"regular expression", map[string]string{"field1": "image", "op1": "matches", "value1": "^postgres:10"}, "Version: 2"},
+		{"not equal", map[string]string{"field1": "image", "op1": "ne", "value1": "postgres:9.6"}, "Version: 2"},
+		{"list contains", map[string]string{"field1": "databases", "op1": "contains", "value1": "logging"}, "Version: 3"},
+		{"list contains an element, not a substring", map[string]string{"field1": "databases", "op1": "contains", "value1": "log"}, "No version found that matches the query."},
+		{"string contains", map[string]string{"field1": "image", "op1": "contains", "value1": ":10"}, "Version: 2"},
+		{"nested object", map[string]string{"field1": "tls.enabled", "value1": "true"}, "Version: 3"},
+		{"selector exists", map[string]string{"field1": "users[username=daniel].password", "op1": "exists"}, "Version: 1"},
+		{"selector equal", map[string]string{"field1": "users[username=daniel].password", "value1": "p3"}, "Version: 4"},
+		{"selector of another element", map[string]string{"field1": "users[username=bob].password", "value1": "p3"}, "No version found that matches the query."},
+		{"bare keys jointly", map[string]string{"field1": "username", "value1": "bob", "field2": "password", "value2": "p1"}, "No version found that matches the query."},
+		{"nested paths jointly", map[string]string{"field1": "users.username", "value1": "daniel", "field2": "users.password", "value2": "p3", "field3": "replicas", "op3": "ge", "value3": "5"}, "Version: 4"},
+		{"nested list not equal", map[string]string{"field1": "users.username", "op1": "ne", "value1": "bob"}, "No version found that matches the query."},
+		{"selector not equal", map[string]string{"field1": "users[username=daniel].password", "op1": "ne", "value1": "p1"}, "Version: 4"},
+		{"deletions never match", map[string]string{"field1": "image", "op1": "ne", "value1": "postgres:9.6", "field2": "image", "op2": "ne", "value2": "postgres:10.1"}, "No version found that matches the query."},
+	}
+	for _, test := range tests {
+		got, err := objLineage.Bisect(test.query)
+		if err != nil || got != test.want {
+			t.Errorf("Version output for TestBisectPredicates() %s was incorrect, got: %s %v, want: %s.\n", test.name, got, err, test.want)
+		}
+	}
+
+	errors := []map[string]string{
+		{"field1": "replicas", "op1": "between", "value1": "3"},
+		{"field1": "replicas", "op1": "gt", "value1": "three"},
+		{"field1": "image", "op1": "matches", "value1": "(postgres"},
+		{"field1": "users[username", "value1": "daniel"},
+		{"field1": "users..password", "value1": "p1"},
+	}
+	for _, query := range errors {
+		if _, err := objLineage.Bisect(query); ReasonForError(err) != ErrorReasonBadRequest {
+			t.Errorf("Error for TestBisectPredicates() %v was incorrect, got: %v, want: %s.\n", query, err, ErrorReasonBadRequest)
+		}
+	}
+
+	//a field that no version has is most likely misspelled
+	if _, err := objLineage.Bisect(map[string]string{"field1": "tls.enabld", "op1": "exists"}); ReasonForError(err) != ErrorReasonInvalid {
+		t.Errorf("Error for TestBisectPredicates() of a missing field was incorrect, got: %v, want: %s.\n", err, ErrorReasonInvalid)
+	}
+}
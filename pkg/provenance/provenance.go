@@ -2,16 +2,18 @@ package provenance
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
-	"time"
+	"sync"
 
 	yaml "gopkg.in/yaml.v2"
 	"k8s.io/apiserver/pkg/apis/audit/v1beta1"
@@ -20,13 +22,20 @@ import (
 var (
 	serviceHost    string
 	servicePort    string
-	Namespace      string
 	httpMethod     string
 	etcdServiceURL string
 
+	// The kind maps, and redactionMap, are replaced together by
+	// ReadKindCompositionFile while holding KindLock. They are never
+	// changed in place.
+	KindLock       sync.RWMutex
 	KindPluralMap  map[string]string
 	kindVersionMap map[string]string
 	compositionMap map[string][]string
+	// kinds whose objects have no namespace, e.g. Namespace or ClusterRole
+	clusterScopedKinds map[string]bool
+	// kind -> short names of its provenance resource
+	shortNamesMap map[string][]string
 
 	REPLICA_SET  string
 	DEPLOYMENT   string
@@ -35,23 +44,48 @@ var (
 	SERVICE      string
 	ETCD_CLUSTER string
 
-	AllProvenanceObjects []ProvenanceOfObject
+	AllProvenanceObjects []*ProvenanceOfObject
+
+	// Guards AllProvenanceObjects and the lineages in it. Ingestion holds
+	// the write lock for each event, queries hold the read lock.
+	StoreLock sync.RWMutex
 )
 
 type Event v1beta1.Event
 
-//for example a postgres
+// for example a postgres
 type ObjectLineage map[int]Spec
 type Spec struct {
 	AttributeToData map[string]interface{}
 	Version         int
 	Timestamp       string
+	// user that made the request
+	Actor string
+	// field path -> field manager, for the fields changed in this version,
+	// e.g. users[username=daniel].password
+	FieldManagers map[string]string
+	// metadata.labels of the object at this version
+	Labels map[string]string
+	// a tombstone has no attributes, it marks the deletion of the object
+	Deleted bool
+	// auditID of the request that changed several objects at once
+	ChangeSet string
+	// apiVersion and kind of the request object, and its spec as it was
+	// sent, after redaction, to rebuild the object of this version
+	APIVersion string
+	Kind       string
+	RawSpec    map[string]interface{}
 }
 
 type ProvenanceOfObject struct {
 	ObjectFullHistory ObjectLineage
-	ResourcePlural    string
-	Name              string
+	// writes to the status subresource, versioned like the spec
+	StatusHistory ObjectLineage
+	// writes to other subresources, which are not part of either lineage
+	Events         []AnnotatedEvent
+	ResourcePlural string
+	Namespace      string
+	Name           string
 }
 
 // Only used when I need to order the AttributeToData map for unit testing
@@ -61,8 +95,8 @@ type pair struct {
 }
 type OrderedMap []pair
 
-//Similar to a map access ..
-//returns Data, ok
+// Similar to a map access ..
+// returns Data, ok
 func (o OrderedMap) At(attrib string) (interface{}, bool) {
 	for _, my_pair := range o {
 		if my_pair.Attribute == attrib {
@@ -74,7 +108,6 @@ func (o OrderedMap) At(attrib string) (interface{}, bool) {
 func init() {
 	serviceHost = os.Getenv("KUBERNETES_SERVICE_HOST")
 	servicePort = os.Getenv("KUBERNETES_SERVICE_PORT")
-	Namespace = "default"
 	httpMethod = http.MethodGet
 
 	etcdServiceURL = "http://example-etcd-cluster-client:2379"
@@ -89,62 +122,117 @@ func init() {
 	KindPluralMap = make(map[string]string)
 	kindVersionMap = make(map[string]string)
 	compositionMap = make(map[string][]string, 0)
-	AllProvenanceObjects = make([]ProvenanceOfObject, 0)
-
-}
-
-func onMinikube() bool {
-	ip := os.Getenv("HOST_IP")
-	fmt.Printf("Minikube IP:%s\n", ip)
-	ipBeginsWith := strings.Split(ip, ".")[0]
-	return ipBeginsWith == "10" || ipBeginsWith == "192"
-	//status.hostIP is set to 10.0.2.15 for onMinikube
-	//and 127.0.0.1 for the real kubernetes server
-	//rc.yaml
-	//- name: HOST_IP
-	//	valueFrom:
-	//		fieldRef:
-	//			fieldPath: status.hostIP
-}
-func CollectProvenance() {
-	fmt.Println("Inside CollectProvenance")
-	readKindCompositionFile()
-	useSample := false
-	if onMinikube() {
-		useSample = true
-		parse(useSample) //using a sample audit log, because
-		//currently audit logging is not supported for minikube
-	} else {
-		for { //keep looping because the audit-logging is live
-			parse(useSample)
-			time.Sleep(time.Second * 5)
-		}
-	}
+	clusterScopedKinds = make(map[string]bool)
+	shortNamesMap = make(map[string][]string)
+	AllProvenanceObjects = make([]*ProvenanceOfObject, 0)
+
 }
 
-func readKindCompositionFile() {
-	// read from the opt file
-	filePath := os.Getenv("KIND_COMPOSITION_FILE")
+// ReadKindCompositionFile loads the tracked kinds, their plurals and
+// redaction rules. Routes are registered from KindPluralMap, so this has
+// to run before the web services are installed.
+// The file is parsed and checked completely before any map is replaced,
+// a file with an error leaves the current kinds in place.
+func ReadKindCompositionFile(filePath string) error {
 	yamlFile, err := ioutil.ReadFile(filePath)
 	if err != nil {
-		fmt.Printf("Error reading file:%s", err)
+		return fmt.Errorf("Error reading file:%s", err)
 	}
 	compositionsList := make([]composition, 0)
 	err = yaml.Unmarshal(yamlFile, &compositionsList)
+	if err != nil {
+		return fmt.Errorf("Error parsing kind compositions:%s", err)
+	}
+	newKindPluralMap := make(map[string]string)
+	newKindVersionMap := make(map[string]string)
+	newCompositionMap := make(map[string][]string)
+	newRedactionMap := make(map[string]*redactionRules)
+	newClusterScopedKinds := make(map[string]bool)
+	newShortNamesMap := make(map[string][]string)
+	plurals := make(map[string]string)
+	shortNames := make(map[string]string)
 	for _, compositionObj := range compositionsList {
 		kind := compositionObj.Kind
 		endpoint := compositionObj.Endpoint
 		composition := compositionObj.Composition
 		plural := compositionObj.Plural
-		KindPluralMap[kind] = plural
-		kindVersionMap[kind] = endpoint
-		compositionMap[kind] = composition
+		if kind == "" || plural == "" {
+			return fmt.Errorf("Error in kind compositions: every entry needs a kind and a plural, got %+v", compositionObj)
+		}
+		if _, ok := newKindPluralMap[kind]; ok {
+			return fmt.Errorf("Error in kind compositions: kind %s is listed twice", kind)
+		}
+		if other, ok := plurals[strings.ToLower(plural)]; ok {
+			return fmt.Errorf("Error in kind compositions: kinds %s and %s have the same plural %s", other, kind, plural)
+		}
+		switch compositionObj.Scope {
+		case "", "Namespaced":
+		case "Cluster":
+			newClusterScopedKinds[kind] = true
+		default:
+			return fmt.Errorf("Error in kind compositions: scope of kind %s must be Namespaced or Cluster, got %s", kind, compositionObj.Scope)
+		}
+		plurals[strings.ToLower(plural)] = kind
+		newKindPluralMap[kind] = plural
+		newKindVersionMap[kind] = endpoint
+		newCompositionMap[kind] = composition
+		newRedactionMap[kind] = newRedactionRules(compositionObj.Redact)
+		for _, shortName := range compositionObj.ShortNames {
+			if other, ok := shortNames[strings.ToLower(shortName)]; ok {
+				return fmt.Errorf("Error in kind compositions: kinds %s and %s have the same short name %s", other, kind, shortName)
+			}
+			shortNames[strings.ToLower(shortName)] = kind
+		}
+		if len(compositionObj.ShortNames) > 0 {
+			newShortNamesMap[kind] = compositionObj.ShortNames
+		}
 	}
+
+	KindLock.Lock()
+	defer KindLock.Unlock()
+	// the file wins over kinds that were discovered from the audit stream
+	for _, k := range discoveredKinds {
+		if canTrack(newKindPluralMap, k) {
+			trackDiscoveredKind(newKindPluralMap, newKindVersionMap, newCompositionMap, newClusterScopedKinds, k)
+		}
+	}
+	KindPluralMap = newKindPluralMap
+	kindVersionMap = newKindVersionMap
+	compositionMap = newCompositionMap
+	clusterScopedKinds = newClusterScopedKinds
+	shortNamesMap = newShortNamesMap
+	redactionMap = newRedactionMap
+	return nil
+}
+
+// TrackedKinds returns the kind -> plural map of the current kind compositions.
+func TrackedKinds() map[string]string {
+	KindLock.RLock()
+	defer KindLock.RUnlock()
+	return KindPluralMap
+}
+
+// ClusterScopedKinds returns the tracked kinds whose objects have no
+// namespace.
+func ClusterScopedKinds() map[string]bool {
+	KindLock.RLock()
+	defer KindLock.RUnlock()
+	return clusterScopedKinds
+}
+
+// KindShortNames returns the short names of the provenance resources of the
+// tracked kinds, from the kind compositions file.
+func KindShortNames() map[string][]string {
+	KindLock.RLock()
+	defer KindLock.RUnlock()
+	return shortNamesMap
 }
 
 func NewProvenanceOfObject() *ProvenanceOfObject {
 	var s ProvenanceOfObject
 	s.ObjectFullHistory = make(map[int]Spec) //need to generalize for other ObjectFullProvenances
+	s.StatusHistory = make(map[int]Spec)
+	s.Events = make([]AnnotatedEvent, 0)
 	return &s
 }
 
@@ -154,10 +242,21 @@ func NewSpec() *Spec {
 	return &s
 }
 
-func FindProvenanceObjectByName(name string, allObjects []ProvenanceOfObject) *ProvenanceOfObject {
+// Objects are identified by resource, namespace and name while events are read.
+// namespace is empty for the objects of cluster scoped kinds.
+func FindProvenanceObject(resourcePlural, namespace, name string) *ProvenanceOfObject {
+	for _, value := range AllProvenanceObjects {
+		if value.ResourcePlural == resourcePlural && value.Namespace == namespace && value.Name == name {
+			return value
+		}
+	}
+	return nil
+}
+
+func FindProvenanceObjectByName(name string, allObjects []*ProvenanceOfObject) *ProvenanceOfObject {
 	for _, value := range allObjects {
 		if name == value.Name {
-			return &value
+			return value
 		}
 	}
 	return nil
@@ -169,6 +268,10 @@ func FindProvenanceObjectByName(name string, allObjects []ProvenanceOfObject) *P
 // when printing
 func (s *Spec) String() string {
 	var b strings.Builder
+	if s.Deleted {
+		fmt.Fprintf(&b, "Version: %d (deleted)\n", s.Version)
+		return b.String()
+	}
 	fmt.Fprintf(&b, "Version: %d\n", s.Version)
 
 	var keys []string
@@ -197,7 +300,7 @@ func (s *Spec) String() string {
 				for _, k1 := range innerkeys {
 					strs = append(strs, fmt.Sprintf("%s: %s", k1, innermap[k1]))
 				}
-				fmt.Fprintf(&b, strings.Join(strs, " "))
+				fmt.Fprint(&b, strings.Join(strs, " "))
 				fmt.Fprintf(&b, "] ")
 			}
 			fmt.Fprintf(&b, "]\n")
@@ -216,7 +319,7 @@ func (o ObjectLineage) String() string {
 	specs := getSpecsInOrder(o)
 
 	for _, spec := range specs {
-		fmt.Fprintf(&b, spec.String())
+		fmt.Fprint(&b, spec.String())
 	}
 	return b.String()
 }
@@ -241,277 +344,148 @@ func (o ObjectLineage) GetVersions() string {
 	specs := getSpecsInOrder(o)
 	outputs := make([]string, 0)
 	for _, spec := range specs {
-		outputs = append(outputs, fmt.Sprintf("%s: Version %d", spec.Timestamp, spec.Version)) //cast int to string
+		output := fmt.Sprintf("%s: Version %d", spec.Timestamp, spec.Version) //cast int to string
+		if spec.Deleted {
+			output = output + " (deleted)"
+		}
+		outputs = append(outputs, output)
 	}
 	return "[" + strings.Join(outputs, ",\n") + "]\n"
 }
 
-//https://stackoverflow.com/questions/23330781/sort-go-map-values-by-keys
+// https://stackoverflow.com/questions/23330781/sort-go-map-values-by-keys
 func (o ObjectLineage) stringInterval(s, e int) string {
 	var b strings.Builder
-	specs := getSpecsInOrder(o)
+	for _, spec := range o.SpecsInInterval(s, e) {
+		fmt.Fprint(&b, spec.String())
+	}
+	return b.String()
+}
 
-	for _, spec := range specs {
+// SpecsInInterval returns the versions from s to e, both included, in order.
+func (o ObjectLineage) SpecsInInterval(s, e int) []Spec {
+	specs := make([]Spec, 0)
+	for _, spec := range getSpecsInOrder(o) {
 		if spec.Version >= s && spec.Version <= e {
-			fmt.Fprintf(&b, spec.String())
+			specs = append(specs, spec)
 		}
 	}
-	return b.String()
+	return specs
 }
 func (o ObjectLineage) SpecHistory() string {
 	return o.String()
 }
 
-func (o ObjectLineage) SpecHistoryInterval(vNumStart, vNumEnd int) string {
+func (o ObjectLineage) SpecHistoryInterval(vNumStart, vNumEnd int) (string, error) {
 	if vNumStart < 0 {
-		return "Invalid start parameter"
+		return "", newInvalidError("start", strconv.Itoa(vNumStart), "Invalid start parameter %d", vNumStart)
 	}
-	return o.stringInterval(vNumStart, vNumEnd)
-}
-func buildAttributeRelationships(specs []Spec, allQueryPairs [][]string) map[string][][]string {
-	// Returns a map from top level attribute to array of pairs (represented as 2 len array)
-	// mapRelationships with one top level object users, looks like this:
-	// ex:	map[users:[[username pallavi] [password pass123]]]
-	mapRelationships := make(map[string][][]string, 0)
-
-	for _, spec := range specs {
-		for _, pair := range allQueryPairs {
-			for mkey, mvalue := range spec.AttributeToData {
-
-				qkey := pair[0] //query key
-				//each qkey qval has to be satisfied
-				vSliceMap, ok := mvalue.([]map[string]string)
-				if ok {
-					for _, mymap := range vSliceMap {
-						for okey, _ := range mymap {
-							if qkey == okey {
-								pairs, ok := mapRelationships[mkey]
-								if ok {
-									pairExistsAlready := false
-									for _, v := range pairs { //check existing pairs
-										//pair[0] is the field, pair[1] is the value.
-										if v[0] == pair[0] {
-											pairExistsAlready = true
-										}
-
-									}
-									//don't want duplicate fields, but want to catch all mapRelationships
-									//over the lineage.
-									if !pairExistsAlready {
-										mapRelationships[mkey] = append(mapRelationships[mkey], pair)
-									}
-								} else {
-									pairs = make([][]string, 0)
-									pairs = append(pairs, pair)
-									mapRelationships[mkey] = pairs
-								}
-
-							}
-						}
-					}
-				}
-			}
-		}
+	if vNumEnd < vNumStart {
+		return "", newInvalidError("end", strconv.Itoa(vNumEnd), "End version %d is before start version %d", vNumEnd, vNumStart)
 	}
-	return mapRelationships
+	return o.stringInterval(vNumStart, vNumEnd), nil
 }
-func buildQueryPairsSlice(queryArgMap map[string]string) ([][]string, error) {
-	allQueryPairs := make([][]string, 0)
-	//this section is storing the mappings from the query, into the allQueryPairs object
-	//these are the queries, each element must be satisfied somewhere in the spec for the bisect to succeed
+
+// buildPredicates parses the fieldN, opN and valueN query parameters, in
+// the order of N. An exists predicate needs no value.
+func buildPredicates(queryArgMap map[string]string) ([]predicate, error) {
+	var fieldNums []int
 	for key, value := range queryArgMap {
-		attributeValueSlice := make([]string, 2)
 		if strings.Contains(key, "field") {
-			//find associated value in argMap, the query params are such that field1=bar, field2=foo
 			fieldNum, err := strconv.Atoi(key[5:])
 			if err != nil {
-				return nil, errors.New(fmt.Sprintf("Failure, could not convert %s. Invalid Query parameters.", key))
-
-			}
-			//find associated value by looking in the map for value+fieldNum.
-			valueOfKey, ok := queryArgMap["value"+strconv.Itoa(fieldNum)]
-			if !ok {
-				return nil, errors.New(fmt.Sprintf("Could not find an associated value for field: %s", key))
+				return nil, newBadRequestError(key, value, "Failure, could not convert %s. Invalid Query parameters.", key)
 			}
-			attributeValueSlice[0] = value
-			attributeValueSlice[1] = valueOfKey
-			allQueryPairs = append(allQueryPairs, attributeValueSlice)
+			fieldNums = append(fieldNums, fieldNum)
 		}
 	}
-	return allQueryPairs, nil
+	sort.Ints(fieldNums)
+	preds := make([]predicate, 0)
+	for _, fieldNum := range fieldNums {
+		n := strconv.Itoa(fieldNum)
+		op := queryArgMap["op"+n]
+		//find associated value by looking in the map for value+fieldNum.
+		valueOfKey, ok := queryArgMap["value"+n]
+		if !ok && op != opExists {
+			return nil, newInvalidError("value"+n, "", "Could not find an associated value for field: %s", "field"+n)
+		}
+		fieldPreds, err := parsePredicates("field"+n, queryArgMap["field"+n], "op"+n, op, "value"+n, valueOfKey)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, fieldPreds...)
+	}
+	return preds, nil
 }
 
 //Steps taken in Bisect are:
 //Sort the spec elements in order of their version number.
 
-//Outer loop is going through each of the versions in order.
-//First I parse the query into a slice of field/value pairs.
-//Then build the map of related fields that belong to the same top level attribute
-// and need to be found in the same underlying map. Then I looped over the
-//field value pairs, searched based on the type.
-func (o ObjectLineage) Bisect(argMap map[string]string) string {
-	specs := getSpecsInOrder(o)
-	allQueryPairs, err := buildQueryPairsSlice(argMap)
-	fmt.Printf("Query Attributes Map: %v\n", allQueryPairs)
-
+// Outer loop is going through each of the versions in order.
+// First I parse the query into a slice of predicates, fieldN opN valueN.
+// Then every version is checked against all of them at once, so predicates
+// on the fields of the same list element, like username and password of
+// users, have to be met by the same element.
+func (o ObjectLineage) Bisect(argMap map[string]string) (string, error) {
+	spec, found, err := o.BisectVersion(argMap)
 	if err != nil {
-		return err.Error()
-	}
-	// fmt.Printf("attributeValuePairs%s\n", allQueryPairs)
-
-	// This method is to build the attributeRelationships from the Query
-	// I Will use this to ensure that fields belonging to the same top-level
-	// attribute, will be treated as a joint query. So you can't just
-	// ask if username ever is daniel and password is ever 223843, because
-	// it could find that in different parts of the spec. They both must be satisfied in the same map object
-	mapRelationships := buildAttributeRelationships(specs, allQueryPairs)
-	fmt.Printf("Query Attributes Same-parent-relationships: %v\n", mapRelationships)
-	// fmt.Println(specs)
-	for _, spec := range specs {
-
-		//every element represents whether a query pair was satisfied. they all must be true.
-		//if they all are true, then that will be the version where the query is first satisfied.
-		andGate := make([]bool, 0)
-		for _, pair := range allQueryPairs {
-			qkey := pair[0]
-			qval := pair[1]
-			satisfied := false
-			for mkey, mvalue := range spec.AttributeToData {
-				//search through the attributes in the spec. Possible types
-				//are string, array of strings, and a map. so I will need to check these
-				//with different search methods
-				vString, ok := mvalue.(string)
-				if ok { //if underlying value is a string
-					satisfied = handleTrivialFields(vString, qkey, qval, mkey)
-					if satisfied {
-						break //qkey/qval was satisfied somewhere in the spec attributes, so move on to the next qkey/qval
-					}
-				}
-
-				vStringSlice, ok := mvalue.([]string)
-				if ok { //if it is a slice of strings
-					satisfied = handleSimpleFields(vStringSlice, qkey, qval, mkey)
-					if satisfied {
-						break //qkey/qval was satisfied somewhere in the spec attributes, so move on to the next qkey/qval
-					}
-				}
+		return "", err
+	}
+	return BisectString(spec, found), nil
+}
 
-				vSliceMap, ok := mvalue.([]map[string]string)
-				//if it is a map, will need to check the underlying data to see
-				//if the qkey/qval exists below the top level attribute. username exists
-				//in the map contained by the 'users' attribute in the spec object, for example.
-				if ok {
-					satisfied = handleCompositeFields(vSliceMap, mapRelationships, qkey, qval, mkey)
-					if satisfied {
-						break //qkey/qval was satisfied somewhere in the spec attributes, so move on to the next qkey/qval
-					}
-				}
-			}
-			andGate = append(andGate, satisfied)
-		}
-		// fmt.Printf("Result of checking all attributes: %v\n", andGate)
-		allTrue := all(andGate)
-		//all indexes in andGate must be true
-		if allTrue {
-			return fmt.Sprintf("Version: %d", spec.Version)
-		}
+// BisectString returns the string representation of the result of a
+// bisect query.
+func BisectString(spec Spec, found bool) string {
+	if found {
+		return fmt.Sprintf("Version: %d", spec.Version)
 	}
 	return "No version found that matches the query."
 }
 
-//this is for a field like deploymentName where the underlying state or data is a string
-func handleTrivialFields(qkey, qval, mkey, fieldData string) bool {
-	if qkey == mkey && qval == fieldData {
-		return true
+// BisectVersion returns the first version that satisfies the query, and
+// false if there is none. Deletions never do.
+func (o ObjectLineage) BisectVersion(argMap map[string]string) (Spec, bool, error) {
+	node, err := o.compileArgs(argMap)
+	if err != nil {
+		return Spec{}, false, err
 	}
-	return false
+	spec, found := o.firstVersion(node)
+	return spec, found, nil
 }
 
-//this is for a field like databases where the underyling state or data is a slice of strings.
-func handleSimpleFields(vStringSlice []string, qkey, qval, mkey string) bool {
-	satisfied := false
-	for _, str := range vStringSlice {
-		if qkey == mkey && qval == str {
-			satisfied = true
-			break
-		}
+// compileArgs builds the query of the fieldN, opN and valueN parameters,
+// and checks that its fields exist.
+func (o ObjectLineage) compileArgs(argMap map[string]string) (*queryNode, error) {
+	node, err := argsNode(argMap)
+	if err != nil {
+		return nil, err
 	}
-	return satisfied
+	return node, node.checkFields(getSpecsInOrder(o))
 }
-func handleCompositeFields(vSliceMap []map[string]string, mapRelationships map[string][][]string, qkey, qval, mkey string) bool {
-	isPossible := false
-	for _, m := range vSliceMap {
-		for k, _ := range m {
-			if k == qkey {
-				isPossible = true
-			}
-		}
-	}
-	if !isPossible {
-		//for example databases qkey will never pass a
-		//composite field test since it is never a key under
-		//the map vSliceMap
-		// fmt.Printf("NOT POSSIBLE, %s %s\n", qkey, mkey)
-		return false
-	}
 
-	for _, mymap := range vSliceMap { //looping through each elem in the mapSlice
-		// check if there is any
-		// other necessary requirements for this mkey.
-		// if key exists, then multiple attributes have to be satisfied
-		// at once for the query to work.
-
-		//For example, say fields username and password belong to
-		//an attribute in the spec called 'users'. The username and password
-		//must be satisfied together in some element of vSliceMap since,
-		//Since It cannot be the case where username is found in index 1 of vSliceMapSlice and password cannot
-		//is found in index 2 of vSliceMapSlice.
-
-		// find all the related attributes associated with the top-level specs
-		// attribute mkey. this would be users for the postgres crd example.
-		attributeCombinedQuery, ok := mapRelationships[mkey]
-		if ok { //ensure multiple attributes are jointly satisfied
-			jointQueryResults := make([]bool, 0)
-			//jointQueryResults is a boolean slice that represents the satisfiability
-			//of the joint query. (all need to be true for it to have found qkey to be true)
-			for _, pair := range attributeCombinedQuery {
-				qckey := pair[0] //for each field/value pair, must find each qckey in
-				//the map object mymap
-				qcval := pair[1]
-				pairSatisfied := false
-				for okey, ovalue := range mymap {
-					if qckey == okey && qcval == ovalue {
-						pairSatisfied = true
-					}
-				}
-				jointQueryResults = append(jointQueryResults, pairSatisfied)
-			}
+// argsNode builds the query of the fieldN, opN and valueN parameters.
+func argsNode(argMap map[string]string) (*queryNode, error) {
+	preds, err := buildPredicates(argMap)
+	if err != nil {
+		return nil, err
+	}
+	return &queryNode{preds: preds}, nil
+}
 
-			allTrue := all(jointQueryResults)
-			// Note: I cannot just return allTrue because this breaks the outer loop ..
-			// The logic goes: if allTrue is never set to True, out of all the elements in outer loop mymap,
-			// then the loop will finish and return false. return allTrue with no if statement, would stop the
-			// loop and only check one mymap! Need to scan through all before returning false
-			if allTrue { //satisfied the joint query
-				return allTrue
-			}
-		} else {
-			//if there is no attribute relationship, but the mapslice type assert was fine,
-			//only need to find an okey in one of the maps, where that query field/value (qkey,qvalue)
-			//is satisfied.
-			for okey, ovalue := range mymap {
-				//If it never returns here, out of all the elements in mymap, Then
-				//the qkey/qval was never found in the attributes within any map in the slice.
-				if qkey == okey && qval == ovalue {
-					return true
-				}
-			}
+func (o ObjectLineage) firstVersion(node *queryNode) (Spec, bool) {
+	for _, spec := range getSpecsInOrder(o) {
+		if spec.Deleted {
+			continue
+		}
+		if node.holds(genericSpec(spec)) {
+			return spec, true
 		}
 	}
-	return false
+	return Spec{}, false
 }
 
-//Method that returns true if all the elements in boolSlice are True
+// Method that returns true if all the elements in boolSlice are True
 func all(boolSlice []bool) bool {
 	allTrue := true
 	for _, b := range boolSlice {
@@ -522,8 +496,8 @@ func all(boolSlice []bool) bool {
 	return allTrue
 }
 
-//Method that compares the elements within 2 mapSlices.
-//Each map must have a corresponding map
+// Method that compares the elements within 2 mapSlices.
+// Each map must have a corresponding map
 func compareMaps(mapSlice1, mapSlice2 []map[string]string) bool {
 	//little trick so that I loop through the bigger map slice,
 	if len(mapSlice2) != len(mapSlice1) {
@@ -562,7 +536,7 @@ func compareMaps(mapSlice1, mapSlice2 []map[string]string) bool {
 	return all(foundMatches)
 }
 
-//Need some way to bring order to the elements of the AttributeToData map,
+// Need some way to bring order to the elements of the AttributeToData map,
 // because otherwise, the output is randomly ordered and I cannot unit test that.
 // so This method orders the map based on the Attribute key and is similar
 // to C++'s pair.
@@ -581,7 +555,10 @@ func (s Spec) OrderedPairs() OrderedMap {
 	}
 	return orderedRet
 }
-func (o ObjectLineage) FullDiff(vNumStart, vNumEnd int) string {
+func (o ObjectLineage) FullDiff(vNumStart, vNumEnd int) (string, error) {
+	if err := o.checkVersions(vNumStart, vNumEnd); err != nil {
+		return "", err
+	}
 	var b strings.Builder
 	sp1 := o[vNumStart].OrderedPairs()
 	sp2 := o[vNumEnd].OrderedPairs()
@@ -607,7 +584,7 @@ func (o ObjectLineage) FullDiff(vNumStart, vNumEnd int) string {
 			fmt.Fprintf(&b, "  Version %d: %s\n", vNumEnd, data1)
 		}
 	}
-	return b.String()
+	return b.String(), nil
 }
 func orderInnerMaps(m []map[string]string) []OrderedMap {
 	orderedMaps := make([]OrderedMap, 0)
@@ -686,7 +663,10 @@ func getDiff(b *strings.Builder, fieldName string, data1, data2 interface{}, vNu
 
 	return b.String()
 }
-func (o ObjectLineage) FieldDiff(fieldName string, vNumStart, vNumEnd int) string {
+func (o ObjectLineage) FieldDiff(fieldName string, vNumStart, vNumEnd int) (string, error) {
+	if err := o.checkVersions(vNumStart, vNumEnd); err != nil {
+		return "", err
+	}
 	var b strings.Builder
 	//Since this is a single field, do not have to do the OrderedMap business like the FullDiff.
 	//Same outp everytime
@@ -694,7 +674,7 @@ func (o ObjectLineage) FieldDiff(fieldName string, vNumStart, vNumEnd int) strin
 	data2, ok2 := o[vNumEnd].AttributeToData[fieldName]
 	switch {
 	case ok1 && ok2:
-		return getDiff(&b, fieldName, data1, data2, vNumStart, vNumEnd)
+		return getDiff(&b, fieldName, data1, data2, vNumStart, vNumEnd), nil
 	case !ok1 && ok2:
 		fmt.Fprintf(&b, "Found diff on attribute %s:\n", fieldName)
 		fmt.Fprintf(&b, "  Version %d: %s\n", vNumStart, "No attribute found.")
@@ -704,118 +684,227 @@ func (o ObjectLineage) FieldDiff(fieldName string, vNumStart, vNumEnd int) strin
 		fmt.Fprintf(&b, "  Version %d: %s\n", vNumStart, data1)
 		fmt.Fprintf(&b, "  Version %d: %s\n", vNumEnd, "No attribute found.")
 	case !ok1 && !ok2:
-		fmt.Fprintf(&b, "Attribute not found in either version %d or %d", vNumStart, vNumEnd)
+		return "", newNotFoundError("field", fieldName, "Attribute %s not found in either version %d or %d", fieldName, vNumStart, vNumEnd)
 	}
-	return b.String()
+	return b.String(), nil
 }
 
-//Ref:https://www.sohamkamani.com/blog/2017/10/18/parsing-json-in-golang/#unstructured-data
-func parse(useSample bool) {
-
-	var log *os.File
-	var err error
-	if useSample {
-		log, err = os.Open("/tmp/minikube-sample-audit.log")
-		if err != nil {
-			fmt.Println(fmt.Sprintf("could not open the log file %s", err))
-			panic(err)
-		}
-	} else {
-		if _, err := os.Stat("/tmp/kube-apiserver-audit.log"); os.IsNotExist(err) {
-			fmt.Println(fmt.Sprintf("could not stat the path %s", err))
-			panic(err)
-		}
-		log, err = os.Open("/tmp/kube-apiserver-audit.log")
-		if err != nil {
-			fmt.Println(fmt.Sprintf("could not open the log file %s", err))
-			panic(err)
-		}
+// Ref:https://www.sohamkamani.com/blog/2017/10/18/parsing-json-in-golang/#unstructured-data
+// Reads the events of the audit log at logPath, starting at offset.
+// Returns the offset after the last complete event that was read, which is
+// where the next pass continues. Stops early when ctx is cancelled.
+func parse(ctx context.Context, logPath string, offset int64) (int64, error) {
+	log, err := os.Open(logPath)
+	if err != nil {
+		return offset, fmt.Errorf("could not open the log file %s", err)
 	}
-
 	defer log.Close()
 
-	scanner := bufio.NewScanner(log)
-	for scanner.Scan() {
-
-		eventJson := scanner.Bytes()
+	//start over if the log was truncated or rotated since the last pass
+	if info, err := log.Stat(); err == nil && info.Size() < offset {
+		offset = 0
+	}
+	if _, err := log.Seek(offset, io.SeekStart); err != nil {
+		return offset, fmt.Errorf("could not seek in the log file %s", err)
+	}
 
-		var event Event
-		err := json.Unmarshal(eventJson, &event)
+	reader := bufio.NewReader(log)
+	for ctx.Err() == nil {
+		line, err := reader.ReadBytes('\n')
 		if err != nil {
-			s := fmt.Sprintf("Problem parsing event's json %s", err)
-			fmt.Println(s)
+			//an incomplete last line is read again on the next pass
+			if err != io.EOF {
+				return offset, fmt.Errorf("could not read the log file %s", err)
+			}
+			break
 		}
+		eventOffset := offset
+		offset += int64(len(line))
 
-		var resourcePlural string
-		var nameOfObject string
-		// var namespace string
-
-		//parse objectRef for unique object identifier and other fields
-		resourcePlural = event.ObjectRef.Resource
-		nameOfObject = event.ObjectRef.Name
-		// namespace = event.ObjectReference.Namespace
-		provObjPtr := FindProvenanceObjectByName(nameOfObject, AllProvenanceObjects)
-		if provObjPtr == nil {
-			//couldnt find object by name, make new provenance object bc This must be new
-			provObjPtr = NewProvenanceOfObject()
-			provObjPtr.ResourcePlural = resourcePlural
-			provObjPtr.Name = nameOfObject
-			AllProvenanceObjects = append(AllProvenanceObjects, *provObjPtr)
+		eventJson := bytes.TrimSpace(line)
+		if len(eventJson) == 0 {
+			continue
 		}
-
-		requestobj := event.RequestObject
-		timestamp := fmt.Sprint(event.RequestReceivedTimestamp.Format("2006-01-02 15:04:05"))
-		//now parse the spec into this provenanceObject that we found or created
-		parseRequestObject(provObjPtr, requestobj.Raw, timestamp)
-	}
-	if err := scanner.Err(); err != nil {
-		panic(err)
+		ingestEvent(eventJson, logPath, eventOffset)
+	}
+	return offset, nil
+}
+
+// Adds the spec carried by a single audit event to the lineage of its object.
+// Returns the problems found with an event that was only partially parsed,
+// and an error for an event that could not be applied at all.
+func processEvent(event *Event) ([]string, error) {
+	var resourcePlural string
+	var nameOfObject string
+	var namespace string
+
+	//a request that failed did not change anything
+	if event.ResponseStatus != nil && event.ResponseStatus.Code >= 400 {
+		eventsSkipped.WithLabelValues(reasonFailedRequest).Inc()
+		return nil, nil
+	}
+	if !discoverKind(event) {
+		eventsSkipped.WithLabelValues(reasonExcluded).Inc()
+		return nil, nil
+	}
+	timestamp := event.RequestReceivedTimestamp.UTC().Format(timestampFormat)
+
+	//parse objectRef for unique object identifier and other fields
+	resourcePlural = event.ObjectRef.Resource
+	nameOfObject = event.ObjectRef.Name
+	namespace = event.ObjectRef.Namespace
+	if event.Verb == "deletecollection" {
+		//there is no name, the request removes every object that matches
+		return nil, processDeleteCollection(event, timestamp)
+	}
+	if nameOfObject == "" && event.RequestObject != nil {
+		nameOfObject = nameFromRequestObject(event.RequestObject.Raw)
+	}
+	provObjPtr := FindProvenanceObject(resourcePlural, namespace, nameOfObject)
+	if provObjPtr == nil {
+		//couldnt find object by name, make new provenance object bc This must be new
+		provObjPtr = NewProvenanceOfObject()
+		provObjPtr.ResourcePlural = resourcePlural
+		provObjPtr.Namespace = namespace
+		provObjPtr.Name = nameOfObject
+		AllProvenanceObjects = append(AllProvenanceObjects, provObjPtr)
+	}
+	if event.Verb == "delete" && event.ObjectRef.Subresource == "" {
+		recordTombstone(provObjPtr, event, timestamp)
+		return nil, nil
+	}
+
+	requestobj := event.RequestObject
+	if requestobj == nil {
+		eventsSkipped.WithLabelValues(reasonNoRequestObject).Inc()
+		return nil, nil
+	}
+	//a subresource body (Scale, a status update) is not the parent's spec
+	if event.ObjectRef.Subresource != "" {
+		return processSubresourceEvent(provObjPtr, event, timestamp), nil
+	}
+	//now parse the spec into this provenanceObject that we found or created
+	//a patch with the object in its body is a server-side apply configuration
+	var applied *appliedConfig
+	if event.Verb == "patch" {
+		applied = newAppliedConfig(managedFieldsOf(event), requestFieldManager(event))
+	}
+	newVersion, problems := parseRequestBody(provObjPtr, requestobj.Raw, timestamp, applied)
+	if newVersion > 0 {
+		recordFieldManagers(provObjPtr.ObjectFullHistory, newVersion, event)
+	} else {
+		eventsSkipped.WithLabelValues(reasonNoSpec).Inc()
 	}
+	return problems, nil
+}
+
+// This method is to parse the bytes of the requestObject attribute of Event,
+// build the spec object, and save that spec to the ObjectLineage map under the next version number.
+// Returns the new version number, or 0 if the request did not carry a spec,
+// and the problems found while building the spec.
+func parseRequestObject(objectProvenance *ProvenanceOfObject, requestObjBytes []byte, timestamp string) (int, []string) {
+	return parseRequestBody(objectProvenance, requestObjBytes, timestamp, nil)
 }
 
-//This method is to parse the bytes of the requestObject attribute of Event,
-//build the spec object, and save that spec to the ObjectLineage map under the next version number.
-func parseRequestObject(objectProvenance *ProvenanceOfObject, requestObjBytes []byte, timestamp string) {
+// parseRequestBody is parseRequestObject for a request that may be a patch.
+// applied is not nil for a patch, a server-side apply configuration only
+// has the fields of its manager and is merged onto the latest version, the
+// elements of the lists with key fields in applied are merged by key.
+func parseRequestBody(objectProvenance *ProvenanceOfObject, requestObjBytes []byte, timestamp string, applied *appliedConfig) (int, []string) {
 	fmt.Println("entering parse request")
 	var result map[string]interface{}
 	json.Unmarshal([]byte(requestObjBytes), &result)
 
-	map1, ok := result["metadata"].(map[string]interface{})
-
-	map2, ok := map1["annotations"].(map[string]interface{})
-	if !ok {
-		//sp, _ := result["spec"].(map[string]interface{})
-		//fmt.Println(sp)
-
-		//TODO: for the case where a crd is first created, the
-		//the annotations spec is empty, which is how subsequent requests are parsed.
-		// instead all the data I want to parse is in the spec field.
-		//Right now it is actually skipping this case.
+	map1, _ := result["metadata"].(map[string]interface{})
+	map2, _ := map1["annotations"].(map[string]interface{})
 
-		return
-	}
+	var raw map[string]interface{}
+	partial := false
 	map3, ok := map2["kubectl.kubernetes.io/last-applied-configuration"].(string)
-	if !ok {
+	switch {
+	case ok:
+		in := []byte(map3)
+		json.Unmarshal(in, &raw)
+	case isFullObject(result):
+		//create, update and server-side apply requests carry the
+		//object itself instead of a last-applied-configuration annotation,
+		//only create and update carry all of its spec
+		raw = result
+		partial = applied != nil
+	default:
 		fmt.Println("Incorrect parsing of the auditEvent.requestObj.metadata")
+		return 0, nil
 	}
-	in := []byte(map3)
-	var raw map[string]interface{}
-	json.Unmarshal(in, &raw)
+	problems := make([]string, 0)
 	spec, ok := raw["spec"].(map[string]interface{})
 	if ok {
 		fmt.Println("Parse was successful!")
 	} else {
 		fmt.Println("Parse was unsuccessful!")
+		problems = append(problems, "Request object has no spec")
+	}
+	//secrets are hashed here, before the spec is built and stored
+	kind, _ := raw["kind"].(string)
+	if kind == "" {
+		kind = kindForPlural(objectProvenance.ResourcePlural)
+	}
+	redactSpec(kind, spec)
+	if latest, ok := latestSpec(objectProvenance.ObjectFullHistory); ok && !latest.Deleted && partial {
+		spec, _ = mergeApplied(genericSpec(latest), spec, "", applied).(map[string]interface{})
 	}
 	newVersion := len(objectProvenance.ObjectFullHistory) + 1
-	newSpec := buildSpec(spec)
+	newSpec, skipped := buildSpec(spec)
 	newSpec.Version = newVersion
 	newSpec.Timestamp = timestamp
+	newSpec.Labels = labelsOf(raw)
+	newSpec.APIVersion, _ = raw["apiVersion"].(string)
+	if newSpec.APIVersion == "" {
+		newSpec.APIVersion = apiVersionOfKind(kind)
+	}
+	newSpec.Kind = kind
+	newSpec.RawSpec = spec
 	objectProvenance.ObjectFullHistory[newVersion] = newSpec
+	countVersion(objectProvenance.ResourcePlural)
 	fmt.Println("exiting parse request")
+	return newVersion, append(problems, skipped...)
+}
+
+func labelsOf(obj map[string]interface{}) map[string]string {
+	labels := make(map[string]string)
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	objLabels, _ := metadata["labels"].(map[string]interface{})
+	for key, value := range objLabels {
+		if str, ok := value.(string); ok {
+			labels[key] = str
+		}
+	}
+	return labels
+}
+
+// A full object has apiVersion, kind and spec. Merge and JSON patches
+// only carry the changed fields, so they are not versions on their own.
+func isFullObject(obj map[string]interface{}) bool {
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	_, hasSpec := obj["spec"].(map[string]interface{})
+	return apiVersion != "" && kind != "" && hasSpec
 }
-func buildSpec(spec map[string]interface{}) Spec {
+
+// Objects that are created by name in the body have no name in objectRef.
+func nameFromRequestObject(requestObjBytes []byte) string {
+	var result struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	}
+	json.Unmarshal(requestObjBytes, &result)
+	return result.Metadata.Name
+}
+
+// Returns the spec, and a problem for every attribute that had to be skipped.
+func buildSpec(spec map[string]interface{}) (Spec, []string) {
 	mySpec := *NewSpec()
+	skipped := make([]string, 0)
 	for attribute, value := range spec {
 		var isMap, isStringSlice, isString, isInt bool
 		//note that I cannot do type assertions because the underlying data
@@ -850,11 +939,14 @@ func buildSpec(spec map[string]interface{}) Spec {
 			mySpec.AttributeToData[attribute] = intField
 		default:
 			fmt.Println("Error with the spec data. not a map slice, float, int, string slice, or string.")
+			skipped = append(skipped, fmt.Sprintf("Attribute %s has an unsupported shape and was skipped", attribute))
 		}
 	}
-	return mySpec
+	return mySpec, skipped
 }
 func printMaps() {
+	KindLock.RLock()
+	defer KindLock.RUnlock()
 	fmt.Println("Printing kindVersionMap")
 	for key, value := range kindVersionMap {
 		fmt.Printf("%s, %s\n", key, value)
@@ -869,7 +961,20 @@ func printMaps() {
 	}
 }
 
+func kindForPlural(plural string) string {
+	KindLock.RLock()
+	defer KindLock.RUnlock()
+	for kind, kindPlural := range KindPluralMap {
+		if strings.EqualFold(kindPlural, plural) {
+			return kind
+		}
+	}
+	return ""
+}
+
 func getResourceKinds() []string {
+	KindLock.RLock()
+	defer KindLock.RUnlock()
 	resourceKindSlice := make([]string, 0)
 	for key, _ := range compositionMap {
 		resourceKindSlice = append(resourceKindSlice, key)
@@ -214,7 +214,7 @@ func TestBisectGeneral(t *testing.T) {
 	argMapTest["field3"] = "password"
 	argMapTest["value3"] = "4557832+#^"
 
-	vOutput := objLineage.Bisect(argMapTest)
+	vOutput, _ := objLineage.Bisect(argMapTest)
 	expected := "Version: 5"
 	if vOutput != expected {
 		t.Errorf("Version output for TestBisectGeneral() was incorrect, got: %s, want: %s.\n", vOutput, expected)
@@ -239,7 +239,7 @@ func TestBisectPasswordChanged(t *testing.T) {
 	argMapTest["field2"] = "password"
 	argMapTest["value2"] = "JEK873BUL!"
 
-	vOutput := objLineage.Bisect(argMapTest)
+	vOutput, _ := objLineage.Bisect(argMapTest)
 	expected := "Version: 6"
 	if vOutput != expected {
 		t.Errorf("Version output for TestBisectPasswordChanged() was incorrect, got: %s, want: %s.\n", vOutput, expected)
@@ -261,7 +261,7 @@ func TestBisectAddDatabase(t *testing.T) {
 	argMapTest["field1"] = "databases"
 	argMapTest["value1"] = "admins"
 
-	vOutput := objLineage.Bisect(argMapTest)
+	vOutput, _ := objLineage.Bisect(argMapTest)
 	expected := "Version: 6"
 	if vOutput != expected {
 		t.Errorf("Version output for TestBisectAddDatabase() was incorrect, got: %s, want: %s.", vOutput, expected)
@@ -284,7 +284,7 @@ func TestBisectAddUser(t *testing.T) {
 	argMapTest["field2"] = "password"
 	argMapTest["value2"] = "apple468"
 
-	vOutput := objLineage.Bisect(argMapTest)
+	vOutput, _ := objLineage.Bisect(argMapTest)
 	expected := "Version: 6"
 	if vOutput != expected {
 		t.Errorf("Version output for TestBisectAddUser() was incorrect, got: %s, want: %s.", vOutput, expected)
@@ -304,7 +304,7 @@ func TestBisectChangeDeploymentName(t *testing.T) {
 	argMapTest["field1"] = "deploymentName"
 	argMapTest["value1"] = "Deployment66"
 
-	vOutput := objLineage.Bisect(argMapTest)
+	vOutput, _ := objLineage.Bisect(argMapTest)
 	expected := "Version: 6"
 	if vOutput != expected {
 		t.Errorf("Version output for TestBisectChangeDeploymentName() was incorrect, got: %s, want: %s.\n", vOutput, expected)
@@ -326,7 +326,7 @@ func TestHistory(t *testing.T) {
 func TestHistoryInterval(t *testing.T) {
 	objLineage, _ := buildLineage()
 
-	vOutput := objLineage.SpecHistoryInterval(2, 4)
+	vOutput, _ := objLineage.SpecHistoryInterval(2, 4)
 	var c conf
 	c.getConf()
 	expected := c.HistoryIntervalTest1
@@ -343,7 +343,7 @@ func TestFieldDiff1(t *testing.T) {
 	newArgs.Version = 6
 	spec6 := makeSpec(newArgs)
 	objLineage[spec6.Version] = spec6
-	output := objLineage.FieldDiff("deploymentName", 5, 6)
+	output, _ := objLineage.FieldDiff("deploymentName", 5, 6)
 	var c conf
 	c.getConf()
 	expected := c.TestFieldDiff1
@@ -364,7 +364,7 @@ func TestFieldDiff2(t *testing.T) {
 	newArgs.Version = 6
 	spec6 := makeSpec(newArgs)
 	objLineage[spec6.Version] = spec6
-	output := objLineage.FieldDiff("databases", 5, 6)
+	output, _ := objLineage.FieldDiff("databases", 5, 6)
 
 	var c conf
 	c.getConf()
@@ -382,7 +382,7 @@ func TestFieldDiff3(t *testing.T) {
 	newArgs.Version = 6
 	spec6 := makeSpec(newArgs)
 	objLineage[spec6.Version] = spec6
-	output := objLineage.FieldDiff("replicas", 5, 6)
+	output, _ := objLineage.FieldDiff("replicas", 5, 6)
 
 	var c conf
 	c.getConf()
@@ -400,7 +400,7 @@ func TestFullDiff1(t *testing.T) {
 	spec6 := makeSpec(newArgs)
 	objLineage[spec6.Version] = spec6
 
-	output := objLineage.FullDiff(5, 6)
+	output, _ := objLineage.FullDiff(5, 6)
 	var c conf
 	c.getConf()
 	expected := c.TestFullDiff1
@@ -419,7 +419,7 @@ func TestFullDiff2(t *testing.T) {
 	spec6 := makeSpec(newArgs)
 	objLineage[spec6.Version] = spec6
 
-	output := objLineage.FullDiff(5, 6)
+	output, _ := objLineage.FullDiff(5, 6)
 	var c conf
 	c.getConf()
 	expected := c.TestFullDiff2
@@ -441,7 +441,7 @@ func TestFullDiff3(t *testing.T) {
 	newArgs.Version = 6
 	spec6 := makeSpec(newArgs)
 	objLineage[spec6.Version] = spec6
-	output := objLineage.FullDiff(5, 6)
+	output, _ := objLineage.FullDiff(5, 6)
 
 	var c conf
 	c.getConf()
@@ -0,0 +1,234 @@
+package provenance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Query is a bisect query as a tree: a predicate on a field, or an and, or
+// or not group of queries. It is the body of a POST to bisect, e.g.
+// {"and": [{"field": "replicas", "op": "gt", "value": 3},
+// {"not": {"field": "image", "op": "matches", "value": "^postgres:9"}}]}
+type Query struct {
+	And []Query `json:"and,omitempty"`
+	Or  []Query `json:"or,omitempty"`
+	Not *Query  `json:"not,omitempty"`
+
+	Field string `json:"field,omitempty"`
+	Op    string `json:"op,omitempty"`
+	// a string, number or boolean, compared as its string
+	Value interface{} `json:"value,omitempty"`
+}
+
+// ParseQuery decodes a query tree from JSON. Unknown keys are an error, so
+// a misspelled key does not silently drop part of the query.
+func ParseQuery(data []byte) (*Query, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var q Query
+	if err := decoder.Decode(&q); err != nil {
+		return nil, newBadRequestError("query", "", "Could not parse the query: %s", err)
+	}
+	return &q, nil
+}
+
+func (q Query) String() string {
+	switch {
+	case len(q.And) > 0:
+		return "(" + joinQueries(q.And, " and ") + ")"
+	case len(q.Or) > 0:
+		return "(" + joinQueries(q.Or, " or ") + ")"
+	case q.Not != nil:
+		return "not " + q.Not.String()
+	}
+	op := q.Op
+	if op == "" {
+		op = opEqual
+	}
+	if op == opExists {
+		return q.Field + " exists"
+	}
+	return fmt.Sprintf("%s %s %v", q.Field, op, q.Value)
+}
+
+func joinQueries(queries []Query, sep string) string {
+	strs := make([]string, 0, len(queries))
+	for _, q := range queries {
+		strs = append(strs, q.String())
+	}
+	return strings.Join(strs, sep)
+}
+
+// A query ready to be checked against the versions. The predicates of a
+// node are met jointly, on top of its and, or and not children.
+type queryNode struct {
+	preds []predicate
+	and   []*queryNode
+	or    []*queryNode
+	not   *queryNode
+}
+
+// compileQuery validates q and builds its node. param is the location of q
+// in the query, for the errors, e.g. query.and[1].
+func compileQuery(q Query, param string) (*queryNode, error) {
+	groups := 0
+	for _, set := range []bool{len(q.And) > 0, len(q.Or) > 0, q.Not != nil, q.Field != ""} {
+		if set {
+			groups++
+		}
+	}
+	if groups != 1 {
+		return nil, newBadRequestError(param, "", "%s must have exactly one of and, or, not and field", param)
+	}
+	if q.Field == "" && (q.Op != "" || q.Value != nil) {
+		return nil, newBadRequestError(param, "", "%s has an op or value without a field", param)
+	}
+	node := &queryNode{}
+	switch {
+	case q.Field != "":
+		value, err := queryValue(param+".value", q.Value)
+		if err != nil {
+			return nil, err
+		}
+		if q.Value == nil && q.Op != opExists {
+			return nil, newInvalidError(param+".value", "", "%s has no value for field %s", param, q.Field)
+		}
+		node.preds, err = parsePredicates(param+".field", q.Field, param+".op", q.Op, param+".value", value)
+		if err != nil {
+			return nil, err
+		}
+	case q.Not != nil:
+		child, err := compileQuery(*q.Not, param+".not")
+		if err != nil {
+			return nil, err
+		}
+		node.not = child
+	case len(q.And) > 0:
+		for i, childQuery := range q.And {
+			child, err := compileQuery(childQuery, fmt.Sprintf("%s.and[%d]", param, i))
+			if err != nil {
+				return nil, err
+			}
+			//the predicates of an and are met jointly, like those of fieldN,
+			//so a field or an and in an and is merged into it
+			if child.not == nil && len(child.or) == 0 {
+				node.preds = append(node.preds, child.preds...)
+				node.and = append(node.and, child.and...)
+				continue
+			}
+			node.and = append(node.and, child)
+		}
+	default:
+		for i, childQuery := range q.Or {
+			child, err := compileQuery(childQuery, fmt.Sprintf("%s.or[%d]", param, i))
+			if err != nil {
+				return nil, err
+			}
+			node.or = append(node.or, child)
+		}
+	}
+	return node, nil
+}
+
+func queryValue(param string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string, float64, bool:
+		return scalarString(v), nil
+	}
+	return "", newBadRequestError(param, "", "%s must be a string, number or boolean", param)
+}
+
+// holds reports whether the spec root, as decoded JSON, meets the query.
+// Only the predicates of a node are met jointly by one list element, its
+// children are checked against the whole spec. A not in an and therefore
+// holds if no element meets it, not just the element the other fields
+// picked, and so does ne.
+func (n *queryNode) holds(root map[string]interface{}) bool {
+	if len(n.preds) > 0 && !matches(root, resolveBareKeys(root, n.preds), 0) {
+		return false
+	}
+	for _, child := range n.and {
+		if !child.holds(root) {
+			return false
+		}
+	}
+	if n.not != nil && n.not.holds(root) {
+		return false
+	}
+	if len(n.or) == 0 {
+		return true
+	}
+	for _, child := range n.or {
+		if child.holds(root) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFields returns an Invalid error for the first field of the query
+// that does not exist in any version of the lineage, most likely a typo.
+func (n *queryNode) checkFields(specs []Spec) error {
+	for _, p := range n.preds {
+		known := false
+		for _, spec := range specs {
+			root := genericSpec(spec)
+			exists := predicate{path: p.path, op: opExists}
+			if !spec.Deleted && matches(root, resolveBareKeys(root, []predicate{exists}), 0) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			field := strings.Join(p.path, ".")
+			return newInvalidError(p.param, field, "Field %s does not exist in any version of the object", field)
+		}
+	}
+	for _, child := range n.children() {
+		if err := child.checkFields(specs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *queryNode) children() []*queryNode {
+	children := append(append([]*queryNode{}, n.and...), n.or...)
+	if n.not != nil {
+		children = append(children, n.not)
+	}
+	return children
+}
+
+// BisectQuery returns the first version that satisfies the query tree q,
+// and false if there is none.
+func (o ObjectLineage) BisectQuery(q *Query) (Spec, bool, error) {
+	node, err := o.compile(q)
+	if err != nil {
+		return Spec{}, false, err
+	}
+	spec, found := o.firstVersion(node)
+	return spec, found, nil
+}
+
+// BisectQueryTransitions returns every run of versions over which the
+// query tree q held, like BisectTransitions.
+func (o ObjectLineage) BisectQueryTransitions(q *Query) ([]QueryRange, error) {
+	node, err := o.compile(q)
+	if err != nil {
+		return nil, err
+	}
+	return o.transitions(node), nil
+}
+
+func (o ObjectLineage) compile(q *Query) (*queryNode, error) {
+	node, err := compileQuery(*q, "query")
+	if err != nil {
+		return nil, err
+	}
+	return node, node.checkFields(getSpecsInOrder(o))
+}
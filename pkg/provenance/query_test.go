@@ -0,0 +1,69 @@
+package provenance
+
+import (
+	"testing"
+)
+
+// Tests that query trees combine predicates with and, or and not, and that
+// they are validated before any version is checked.
+func TestBisectQuery(t *testing.T) {
+	objLineage := buildNestedLineage()
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"and", `{"and": [{"field": "replicas", "op": "ge", "value": 3}, {"field": "tls.enabled", "value": true}]}`, 3},
+		{"or", `{"or": [{"field": "databases", "op": "contains", "value": "logging"}, {"field": "image", "value": "postgres:10.1"}]}`, 2},
+		{"not", `{"and": [{"not": {"field": "image", "op": "matches", "value": "^postgres:9"}}, {"field": "replicas", "op": "gt", "value": 3}]}`, 3},
+		{"and of list elements", `{"and": [{"field": "username", "value": "daniel"}, {"field": "password", "value": "p3"}]}`, 4},
+		{"never", `{"and": [{"field": "username", "value": "bob"}, {"field": "password", "value": "p1"}]}`, 0},
+		{"not of no element", `{"and": [{"field": "username", "value": "bob"}, {"not": {"field": "password", "value": "p1"}}]}`, 4},
+		{"ne of no element", `{"and": [{"field": "username", "value": "bob"}, {"field": "password", "op": "ne", "value": "p1"}]}`, 4},
+		{"ne with a selector", `{"field": "users[username=bob].password", "op": "ne", "value": "p1"}`, 1},
+	}
+	for _, test := range tests {
+		q, err := ParseQuery([]byte(test.query))
+		if err != nil {
+			t.Errorf("Error for TestBisectQuery() %s was incorrect, got: %v, want: none.\n", test.name, err)
+			continue
+		}
+		spec, found, err := objLineage.BisectQuery(q)
+		if err != nil || found != (test.want > 0) || (found && spec.Version != test.want) {
+			t.Errorf("Version for TestBisectQuery() %s was incorrect, got: %d %t %v, want: %d.\n", test.name, spec.Version, found, err, test.want)
+		}
+	}
+
+	q, _ := ParseQuery([]byte(`{"or": [{"field": "replicas", "value": 1}, {"field": "replicas", "value": 5}]}`))
+	ranges, err := objLineage.BisectQueryTransitions(q)
+	if err != nil || len(ranges) != 2 || ranges[0].Last != 1 || ranges[1].Start.Version != 3 {
+		t.Errorf("Transitions for TestBisectQuery() were incorrect, got: %v %v, want runs from version 1 and 3.\n", ranges, err)
+	}
+	if got := q.String(); got != "(replicas eq 1 or replicas eq 5)" {
+		t.Errorf("String for TestBisectQuery() was incorrect, got: %s, want: (replicas eq 1 or replicas eq 5).\n", got)
+	}
+
+	errors := []struct {
+		query  string
+		reason ErrorReason
+	}{
+		{`{"feild": "replicas", "value": 3}`, ErrorReasonBadRequest},
+		{`{"and": [{"field": "replicas", "value": 3}, {"or": []}]}`, ErrorReasonBadRequest},
+		{`{"field": "replicas", "value": 3, "not": {"field": "image", "value": "x"}}`, ErrorReasonBadRequest},
+		{`{"field": "replicas", "value": {"a": 1}}`, ErrorReasonBadRequest},
+		{`{"field": "replicas", "op": "between", "value": 3}`, ErrorReasonBadRequest},
+		{`{"and": [{"field": "replicas"}]}`, ErrorReasonInvalid},
+		{`{"or": [{"field": "replicas", "value": 3}, {"not": {"field": "replicaz", "value": 3}}]}`, ErrorReasonInvalid},
+		{`not json`, ErrorReasonBadRequest},
+	}
+	for _, test := range errors {
+		q, err := ParseQuery([]byte(test.query))
+		if err == nil {
+			_, _, err = objLineage.BisectQuery(q)
+		}
+		if reason := ReasonForError(err); reason != test.reason {
+			t.Errorf("Error for TestBisectQuery() %s was incorrect, got: %s (%v), want: %s.\n", test.query, reason, err, test.reason)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package provenance
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Prefix of every value that was replaced by redactValue. The rest of the
+// marker is a salted hash, so two versions holding the same secret render
+// the same marker and a changed secret shows up in diffs and bisect.
+const redactedPrefix = "<redacted:"
+
+var (
+	// kind -> redaction rules, built from the redact section of the
+	// kind compositions file
+	redactionMap  map[string]*redactionRules
+	redactionSalt []byte
+)
+
+type redactionRules struct {
+	// paths relative to spec, list elements are stepped through
+	// without an index, e.g. users.password
+	fields      map[string]bool
+	keyPatterns []*regexp.Regexp
+}
+
+func init() {
+	redactionMap = make(map[string]*redactionRules)
+	redactionSalt = []byte(os.Getenv("REDACTION_SALT"))
+	if len(redactionSalt) == 0 {
+		// No salt configured, so use one that lives as long as the process.
+		// Hashes are only compared against each other within one run.
+		redactionSalt = make([]byte, 16)
+		if _, err := rand.Read(redactionSalt); err != nil {
+			fmt.Printf("Could not generate redaction salt: %s\n", err)
+		}
+	}
+}
+
+// SetRedactionSalt replaces the salt of the hashes of redacted values. It
+// has to be called before any event is read, the hashes of earlier
+// versions would not match those of later ones.
+func SetRedactionSalt(salt []byte) {
+	redactionSalt = salt
+}
+
+func newRedactionRules(r redaction) *redactionRules {
+	rules := &redactionRules{fields: make(map[string]bool)}
+	for _, field := range r.Fields {
+		rules.fields[strings.TrimPrefix(field, "spec.")] = true
+	}
+	for _, pattern := range r.KeyPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			fmt.Printf("Ignoring invalid redaction key pattern %s: %s\n", pattern, err)
+			continue
+		}
+		rules.keyPatterns = append(rules.keyPatterns, re)
+	}
+	return rules
+}
+
+func (r *redactionRules) empty() bool {
+	return r == nil || (len(r.fields) == 0 && len(r.keyPatterns) == 0)
+}
+
+func (r *redactionRules) matches(path, key string) bool {
+	if r.fields[path] {
+		return true
+	}
+	for _, re := range r.keyPatterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactSpec replaces every value of spec that is matched by the redaction
+// rules of kind with its salted hash. It works on the raw spec, before
+// buildSpec runs, so secrets are never stored in a Spec.
+func redactSpec(kind string, spec map[string]interface{}) {
+	KindLock.RLock()
+	rules := redactionMap[kind]
+	KindLock.RUnlock()
+	if rules.empty() {
+		return
+	}
+	redactMap(rules, "", spec)
+}
+
+func redactMap(rules *redactionRules, parent string, m map[string]interface{}) {
+	for key, value := range m {
+		path := key
+		if parent != "" {
+			path = parent + "." + key
+		}
+		if rules.matches(path, key) {
+			m[key] = redactValue(value)
+			continue
+		}
+		redactChild(rules, path, value)
+	}
+}
+
+func redactChild(rules *redactionRules, path string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redactMap(rules, path, v)
+	case []interface{}:
+		for _, elem := range v {
+			redactChild(rules, path, elem)
+		}
+	}
+}
+
+// redactValue returns the marker stored in place of value. Non string
+// values (maps, lists, numbers) are hashed over their JSON encoding.
+func redactValue(value interface{}) string {
+	str, ok := value.(string)
+	if !ok {
+		bytes, err := json.Marshal(value)
+		if err != nil {
+			str = fmt.Sprint(value)
+		} else {
+			str = string(bytes)
+		}
+	}
+	sum := sha256.Sum256(append(append([]byte{}, redactionSalt...), str...))
+	return redactedPrefix + hex.EncodeToString(sum[:6]) + ">"
+}
+
+func isRedacted(data string) bool {
+	return strings.HasPrefix(data, redactedPrefix)
+}
+
+// valuesMatch compares stored spec data with a value taken from a query.
+// A redacted value only matches its own marker, as history shows it. The
+// query value is not hashed, that would let bisect confirm guesses of the
+// secret.
+func valuesMatch(data, queryValue string) bool {
+	return data == queryValue
+}
@@ -0,0 +1,82 @@
+package provenance
+
+import (
+	"strings"
+	"testing"
+)
+
+// Builds the raw request object of a kubectl apply for a Postgres,
+// the spec is stored in the last-applied-configuration annotation.
+func postgresRequestObject(lastApplied string) []byte {
+	quoted := strings.Replace(lastApplied, `"`, `\"`, -1)
+	return []byte(`{"metadata":{"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"` + quoted + `"}}}`)
+}
+
+func withRedaction(kind string, r redaction) func() {
+	old := redactionMap[kind]
+	redactionMap[kind] = newRedactionRules(r)
+	return func() { redactionMap[kind] = old }
+}
+
+// Tests that secrets matched by field path or key pattern never reach the
+// stored spec, and that the hashes still show when a secret changed.
+func TestRedactionAtIngestion(t *testing.T) {
+	defer withRedaction("Postgres", redaction{
+		Fields:      []string{"spec.users.password"},
+		KeyPatterns: []string{"(?i)token$"},
+	})()
+
+	provObj := NewProvenanceOfObject()
+	provObj.ResourcePlural = "postgreses"
+	provObj.Name = "client25"
+	parseRequestObject(provObj, postgresRequestObject(`{"kind":"Postgres","spec":{"apiToken":"tok-xyz","users":[{"username":"daniel","password":"pass123"}]}}`), "2006-01-02 15:04:05")
+	parseRequestObject(provObj, postgresRequestObject(`{"kind":"Postgres","spec":{"apiToken":"tok-xyz","users":[{"username":"daniel","password":"pass456"}]}}`), "2006-01-02 15:04:06")
+
+	history := provObj.ObjectFullHistory.SpecHistory()
+	for _, secret := range []string{"pass123", "pass456", "tok-xyz"} {
+		if strings.Contains(history, secret) {
+			t.Errorf("Spec history for TestRedactionAtIngestion() contains secret %s: %s\n", secret, history)
+		}
+	}
+	if !strings.Contains(history, "username: daniel") {
+		t.Errorf("Spec history for TestRedactionAtIngestion() lost a non secret field: %s\n", history)
+	}
+
+	if diff, err := provObj.ObjectFullHistory.FieldDiff("apiToken", 1, 2); diff != "" || err != nil {
+		t.Errorf("Diff output for TestRedactionAtIngestion() was incorrect, got: %s %v, want no diff.\n", diff, err)
+	}
+	diff, _ := provObj.ObjectFullHistory.FieldDiff("users", 1, 2)
+	if !strings.Contains(diff, "Found diff on attribute users") || strings.Contains(diff, "pass456") {
+		t.Errorf("Diff output for TestRedactionAtIngestion() was incorrect, got: %s\n", diff)
+	}
+}
+
+// Tests that a bisect query finds the version holding a redacted value by
+// its marker, and not by the plain text secret.
+func TestBisectRedactedField(t *testing.T) {
+	objLineage, newArgs := buildLineage()
+	users := updateUser(newArgs.Users, "daniel", redactValue("JEK873BUL!"))
+	newArgs.Users = users
+	newArgs.Version = 6
+	spec6 := makeSpec(newArgs)
+	objLineage[spec6.Version] = spec6
+
+	argMapTest := make(map[string]string, 0)
+	argMapTest["field1"] = "username"
+	argMapTest["value1"] = "daniel"
+	argMapTest["field2"] = "password"
+	argMapTest["value2"] = "JEK873BUL!"
+
+	vOutput, _ := objLineage.Bisect(argMapTest)
+	expected := "No version found that matches the query."
+	if vOutput != expected {
+		t.Errorf("Version output for TestBisectRedactedField() was incorrect, got: %s, want: %s.\n", vOutput, expected)
+	}
+
+	argMapTest["value2"] = redactValue("JEK873BUL!")
+	vOutput, _ = objLineage.Bisect(argMapTest)
+	expected = "Version: 6"
+	if vOutput != expected {
+		t.Errorf("Version output for TestBisectRedactedField() was incorrect, got: %s, want: %s.\n", vOutput, expected)
+	}
+}
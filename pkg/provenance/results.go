@@ -0,0 +1,77 @@
+package provenance
+
+import (
+	"reflect"
+	"sort"
+)
+
+// A difference of one attribute between two versions, for the structured
+// diff. From or To is nil if the attribute does not exist in that version.
+type AttributeDiff struct {
+	Attribute string
+	From      interface{}
+	To        interface{}
+}
+
+// SpecsInOrder returns the versions of the lineage, oldest first.
+func (o ObjectLineage) SpecsInOrder() []Spec {
+	return getSpecsInOrder(o)
+}
+
+// AttributeDiffs returns the attributes that differ between the versions
+// vNumStart and vNumEnd, in sorted order. If fieldName is not empty only
+// that attribute is compared. Lists are compared without regard to order,
+// like FullDiff and FieldDiff do. The errors are those of FieldDiff.
+func (o ObjectLineage) AttributeDiffs(fieldName string, vNumStart, vNumEnd int) ([]AttributeDiff, error) {
+	if err := o.checkVersions(vNumStart, vNumEnd); err != nil {
+		return nil, err
+	}
+	data1 := o[vNumStart].AttributeToData
+	data2 := o[vNumEnd].AttributeToData
+	attributes := make(map[string]bool)
+	for attribute := range data1 {
+		attributes[attribute] = true
+	}
+	for attribute := range data2 {
+		attributes[attribute] = true
+	}
+	var keys []string
+	for attribute := range attributes {
+		if fieldName == "" || attribute == fieldName {
+			keys = append(keys, attribute)
+		}
+	}
+	if fieldName != "" && len(keys) == 0 {
+		return nil, newNotFoundError("field", fieldName, "Attribute %s not found in either version %d or %d", fieldName, vNumStart, vNumEnd)
+	}
+	sort.Strings(keys)
+
+	diffs := make([]AttributeDiff, 0)
+	for _, attribute := range keys {
+		value1, ok1 := data1[attribute]
+		value2, ok2 := data2[attribute]
+		if ok1 && ok2 && !attributeDataDiffers(value1, value2) {
+			continue
+		}
+		diffs = append(diffs, AttributeDiff{Attribute: attribute, From: value1, To: value2})
+	}
+	return diffs, nil
+}
+
+func attributeDataDiffers(data1, data2 interface{}) bool {
+	strArray1, ok1 := data1.([]string)
+	strArray2, ok2 := data2.([]string)
+	if ok1 && ok2 {
+		sorted1 := append([]string{}, strArray1...)
+		sorted2 := append([]string{}, strArray2...)
+		sort.Strings(sorted1)
+		sort.Strings(sorted2)
+		return !reflect.DeepEqual(sorted1, sorted2)
+	}
+	strMap1, ok1 := data1.([]map[string]string)
+	strMap2, ok2 := data2.([]map[string]string)
+	if ok1 && ok2 {
+		return !compareMaps(strMap1, strMap2)
+	}
+	return !reflect.DeepEqual(data1, data2)
+}
@@ -0,0 +1,38 @@
+package provenance
+
+import (
+	"testing"
+)
+
+// Tests that the structured diff reports the changed attributes, and
+// ignores a reordered list.
+func TestAttributeDiffs(t *testing.T) {
+	objLineage, newArgs := buildLineage()
+	newArgs.DeploymentName = "Deployment66"
+	newArgs.Databases = []string{newArgs.Databases[1], newArgs.Databases[0], newArgs.Databases[2], newArgs.Databases[3]}
+	newArgs.Version = 6
+	spec6 := makeSpec(newArgs)
+	objLineage[spec6.Version] = spec6
+
+	diffs, _ := objLineage.AttributeDiffs("", 5, 6)
+	if len(diffs) != 1 || diffs[0].Attribute != "deploymentName" || diffs[0].To != "Deployment66" {
+		t.Errorf("Diffs for TestAttributeDiffs() were incorrect, got: %v, want: deploymentName to Deployment66.\n", diffs)
+	}
+	if diffs, _ := objLineage.AttributeDiffs("databases", 3, 6); len(diffs) != 1 || diffs[0].From == nil {
+		t.Errorf("Field diffs for TestAttributeDiffs() were incorrect, got: %v, want: databases.\n", diffs)
+	}
+}
+
+// Tests that BisectVersion returns the spec that Bisect reports.
+func TestBisectVersion(t *testing.T) {
+	objLineage, _ := buildLineage()
+	argMapTest := map[string]string{"field1": "databases", "value1": "logging"}
+	spec, found, err := objLineage.BisectVersion(argMapTest)
+	if err != nil || !found || spec.Version != 4 {
+		t.Errorf("Version for TestBisectVersion() was incorrect, got: %d %v %v, want: 4.\n", spec.Version, found, err)
+	}
+	argMapTest = map[string]string{"field1": "databases", "value1": "missing"}
+	if _, found, _ := objLineage.BisectVersion(argMapTest); found {
+		t.Errorf("Version for TestBisectVersion() was found for a value that never existed.\n")
+	}
+}
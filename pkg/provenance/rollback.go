@@ -0,0 +1,278 @@
+package provenance
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PatchType is the kind of patch a rollback is computed as.
+type PatchType string
+
+const (
+	// JSON merge patch, RFC 7386, kubectl patch --type merge
+	MergePatchType PatchType = "merge"
+	// strategic merge patch, kubectl patch --type strategic. Lists of
+	// objects are replaced as a whole, so the merge keys of the kind
+	// do not need to be known. Only built-in kinds have the schema it
+	// needs, custom resources answer 415.
+	StrategicMergePatchType PatchType = "strategic"
+	// apply configuration, the fields of the object the applier owns,
+	// for kubectl apply --server-side
+	ApplyPatchType PatchType = "apply"
+)
+
+// RollbackPatch returns the patch that moves the object from its latest
+// version back to version. If fields, paths relative to spec such as
+// databases or tls.enabled, are given only they are touched, otherwise the
+// whole spec and the labels are. A patch that would write a redacted value
+// is refused, the secret is not known.
+func (p *ProvenanceOfObject) RollbackPatch(version int, patchType PatchType, fields []string) (map[string]interface{}, error) {
+	switch patchType {
+	case MergePatchType, StrategicMergePatchType, ApplyPatchType:
+	default:
+		return nil, newBadRequestError("type", string(patchType), "Unknown patch type %s, want merge, strategic or apply", patchType)
+	}
+	target, ok := p.ObjectFullHistory[version]
+	if !ok {
+		return nil, newNotFoundError("version", strconv.Itoa(version), "Version %d not found", version)
+	}
+	if target.Deleted {
+		return nil, newInvalidError("version", strconv.Itoa(version), "Version %d records the deletion of the object, there is nothing to roll back to", version)
+	}
+	latest, _ := latestSpec(p.ObjectFullHistory)
+	if latest.Deleted {
+		return nil, newInvalidError("version", strconv.Itoa(version),
+			"The object was deleted in version %d, it can not be patched, apply versions/%d instead", latest.Version, version)
+	}
+	strategic := patchType == StrategicMergePatchType
+	if strategic && !builtInAPIVersion(p.apiVersion(latest)) {
+		return nil, newBadRequestError("type", string(patchType),
+			"Resource %s is not a built-in kind, it does not take strategic merge patches, use merge or apply", p.ResourcePlural)
+	}
+	fromSpec, toSpec := rawSpecOf(latest), rawSpecOf(target)
+
+	var patch map[string]interface{}
+	switch {
+	case patchType == ApplyPatchType:
+		patch, err := p.applyConfiguration(target, toSpec, fields)
+		if err != nil {
+			return nil, err
+		}
+		return patch, checkRedacted(patch, "", version)
+	case len(fields) == 0:
+		patch = make(map[string]interface{})
+		if spec := mergeDiff(fromSpec, toSpec, strategic); len(spec) > 0 {
+			patch["spec"] = spec
+		}
+		if labels := mergeDiff(labelMap(latest.Labels), labelMap(target.Labels), false); len(labels) > 0 {
+			patch["metadata"] = map[string]interface{}{"labels": labels}
+		}
+	default:
+		spec := make(map[string]interface{})
+		for _, field := range fields {
+			path := strings.Split(strings.TrimPrefix(field, "spec."), ".")
+			from, inFrom := valueAt(fromSpec, path)
+			to, inTo := valueAt(toSpec, path)
+			switch {
+			case !inFrom && !inTo:
+				return nil, newNotFoundError("fields", field, "Field %s not found in version %d or the latest version %d", field, version, latest.Version)
+			case !inTo:
+				setValueAt(spec, path, nil)
+			default:
+				if d, changed := diffValue(from, to, strategic); changed {
+					setValueAt(spec, path, d)
+				}
+			}
+		}
+		patch = make(map[string]interface{})
+		if len(spec) > 0 {
+			patch["spec"] = spec
+		}
+	}
+	return patch, checkRedacted(patch, "", version)
+}
+
+// The apiVersion of the object in spec, or of its kind in the kind
+// compositions file for a version recorded without one.
+func (p *ProvenanceOfObject) apiVersion(spec Spec) string {
+	if spec.APIVersion != "" {
+		return spec.APIVersion
+	}
+	return apiVersionOfKind(kindForPlural(p.ResourcePlural))
+}
+
+// builtInAPIVersion reports whether apiVersion is served by Kubernetes
+// itself: the core group, a group without a dot such as apps, or one under
+// k8s.io. The groups under x-k8s.io are defined by custom resources.
+func builtInAPIVersion(apiVersion string) bool {
+	slash := strings.Index(apiVersion, "/")
+	if slash < 0 {
+		return apiVersion != ""
+	}
+	group := apiVersion[:slash]
+	if strings.HasSuffix(group, ".x-k8s.io") {
+		return false
+	}
+	return !strings.Contains(group, ".") || strings.HasSuffix(group, ".k8s.io")
+}
+
+// An apply configuration holds the target values of the fields, or the
+// whole spec and the labels. A field can not be removed by leaving it out,
+// only the field manager that owns it would lose it, so that is refused.
+func (p *ProvenanceOfObject) applyConfiguration(target Spec, toSpec map[string]interface{}, fields []string) (map[string]interface{}, error) {
+	manifest, err := p.manifest(target.Version)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return manifest, nil
+	}
+	spec := make(map[string]interface{})
+	for _, field := range fields {
+		path := strings.Split(strings.TrimPrefix(field, "spec."), ".")
+		to, ok := valueAt(toSpec, path)
+		if !ok {
+			return nil, newInvalidError("fields", field,
+				"Field %s does not exist in version %d, an apply configuration can not remove it, use a merge patch", field, target.Version)
+		}
+		setValueAt(spec, path, to)
+	}
+	metadata, _ := manifest["metadata"].(map[string]interface{})
+	delete(metadata, "labels")
+	manifest["spec"] = spec
+	return manifest, nil
+}
+
+// The spec as it was sent, or the built attributes of a version that was
+// recorded without a request object.
+func rawSpecOf(spec Spec) map[string]interface{} {
+	if spec.RawSpec != nil {
+		return spec.RawSpec
+	}
+	return spec.AttributeToData
+}
+
+func labelMap(labels map[string]string) map[string]interface{} {
+	m := make(map[string]interface{})
+	for key, value := range labels {
+		m[key] = value
+	}
+	return m
+}
+
+// mergeDiff returns the merge patch from from to to: the changed and added
+// fields with their new values and the removed fields set to null.
+func mergeDiff(from, to map[string]interface{}, strategic bool) map[string]interface{} {
+	patch := make(map[string]interface{})
+	for key, toValue := range to {
+		if d, changed := diffValue(from[key], toValue, strategic); changed {
+			patch[key] = d
+		}
+	}
+	for key := range from {
+		if _, ok := to[key]; !ok {
+			patch[key] = nil
+		}
+	}
+	return patch
+}
+
+// diffValue returns the patch of a single value. Objects are patched field
+// by field, anything else is replaced.
+func diffValue(from, to interface{}, strategic bool) (interface{}, bool) {
+	fromMap, fromIsMap := from.(map[string]interface{})
+	toMap, toIsMap := to.(map[string]interface{})
+	if fromIsMap && toIsMap {
+		patch := mergeDiff(fromMap, toMap, strategic)
+		return patch, len(patch) > 0
+	}
+	if reflect.DeepEqual(from, to) {
+		return nil, false
+	}
+	if list, ok := to.([]interface{}); ok && strategic && isObjectList(list) {
+		//without the directive the elements would be merged by their merge key
+		return append([]interface{}{map[string]interface{}{"$patch": "replace"}}, list...), true
+	}
+	return to, true
+}
+
+func isObjectList(list []interface{}) bool {
+	for _, elem := range list {
+		if _, ok := elem.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return len(list) > 0
+}
+
+func valueAt(m map[string]interface{}, path []string) (interface{}, bool) {
+	var value interface{} = m
+	for _, key := range path {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func setValueAt(m map[string]interface{}, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		child, ok := m[key].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			m[key] = child
+		}
+		m = child
+	}
+	m[path[len(path)-1]] = value
+}
+
+// checkRedacted returns an Invalid error if the patch holds a redacted
+// value, applying it would overwrite the secret with its marker.
+func checkRedacted(value interface{}, path string, version int) error {
+	if field := redactedField(value, path); field != "" {
+		return newInvalidError("fields", strings.TrimPrefix(field, "spec."),
+			"Field %s is redacted in version %d, its value can not be restored, leave it out with the fields parameter", field, version)
+	}
+	return nil
+}
+
+// redactedField returns the path of a redacted value in value, which is
+// at path, or an empty string if it has none. The keys of a map are
+// visited in order, so the same field is reported every time.
+func redactedField(value interface{}, path string) string {
+	switch v := value.(type) {
+	case string:
+		if isRedacted(v) {
+			return path
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			if field := redactedField(v[key], childPath); field != "" {
+				return field
+			}
+		}
+	case []interface{}:
+		for _, elem := range v {
+			if field := redactedField(elem, path); field != "" {
+				return field
+			}
+		}
+	}
+	return ""
+}
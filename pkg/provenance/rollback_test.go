@@ -0,0 +1,79 @@
+package provenance
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Tests that the rollback patches move the latest version back to an
+// earlier one, of all fields or only the chosen ones, and that a patch
+// never writes a redacted value.
+func TestRollbackPatch(t *testing.T) {
+	defer withRedaction("Postgres", redaction{Fields: []string{"spec.users.password"}})()
+
+	provObj := NewProvenanceOfObject()
+	provObj.ResourcePlural = "postgreses"
+	provObj.Namespace = "default"
+	provObj.Name = "client25"
+	parseRequestObject(provObj, postgresRequestObject(`{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"client25","labels":{"tier":"front"}},"spec":{"databases":["a","b"],"tls":{"enabled":true,"cert":"c1"},"users":[{"username":"daniel","password":"pass123"}]}}`), "2018-08-05 00:16:20")
+	parseRequestObject(provObj, postgresRequestObject(`{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"client25","labels":{"tier":"back"}},"spec":{"databases":["a"],"tls":{"enabled":false},"backup":"daily","users":[{"username":"daniel","password":"pass123"}]}}`), "2018-08-05 00:17:20")
+	parseRequestObject(provObj, postgresRequestObject(`{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"client25","labels":{"tier":"back"}},"spec":{"databases":["a"],"tls":{"enabled":false},"backup":"daily","users":[{"username":"daniel","password":"pass456"}]}}`), "2018-08-05 00:18:20")
+
+	tests := []struct {
+		version   int
+		patchType PatchType
+		fields    []string
+		want      string
+	}{
+		{3, MergePatchType, nil, `{}`},
+		{1, MergePatchType, []string{"databases", "tls.cert", "backup"}, `{"spec":{"backup":null,"databases":["a","b"],"tls":{"cert":"c1"}}}`},
+		{1, ApplyPatchType, []string{"databases", "tls.enabled"}, `{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"client25","namespace":"default"},"spec":{"databases":["a","b"],"tls":{"enabled":true}}}`},
+	}
+	for _, test := range tests {
+		patch, err := provObj.RollbackPatch(test.version, test.patchType, test.fields)
+		if err != nil {
+			t.Errorf("Error for TestRollbackPatch() to version %d was incorrect, got: %v, want: none.\n", test.version, err)
+			continue
+		}
+		got, _ := json.Marshal(patch)
+		if string(got) != test.want {
+			t.Errorf("%s patch for TestRollbackPatch() to version %d was incorrect, got: %s, want: %s.\n", test.patchType, test.version, got, test.want)
+		}
+	}
+
+	//a list of strings is replaced without a directive, a list of objects with one
+	deployment := NewProvenanceOfObject()
+	deployment.ResourcePlural = "deployments"
+	deployment.Namespace = "default"
+	deployment.Name = "web"
+	parseRequestObject(deployment, postgresRequestObject(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web"},"spec":{"replicas":1,"args":["a"],"containers":[{"name":"web","image":"nginx:1.14"}]}}`), "2018-08-05 00:16:20")
+	parseRequestObject(deployment, postgresRequestObject(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web"},"spec":{"replicas":1,"args":["a","b"],"containers":[{"name":"web","image":"nginx:1.15"}]}}`), "2018-08-05 00:17:20")
+	patch, err := deployment.RollbackPatch(1, StrategicMergePatchType, nil)
+	if got, _ := json.Marshal(patch); err != nil || string(got) != `{"spec":{"args":["a"],"containers":[{"$patch":"replace"},{"image":"nginx:1.14","name":"web"}]}}` {
+		t.Errorf("Strategic patch for TestRollbackPatch() was incorrect, got: %s %v.\n", got, err)
+	}
+
+	errors := []struct {
+		version   int
+		patchType PatchType
+		fields    []string
+		reason    ErrorReason
+	}{
+		{9, MergePatchType, nil, ErrorReasonNotFound},
+		{1, "json", nil, ErrorReasonBadRequest},
+		//custom resources do not take strategic merge patches
+		{1, StrategicMergePatchType, []string{"tls"}, ErrorReasonBadRequest},
+		{1, MergePatchType, []string{"missing"}, ErrorReasonNotFound},
+		{1, ApplyPatchType, []string{"users"}, ErrorReasonInvalid},
+		{1, ApplyPatchType, []string{"backup"}, ErrorReasonInvalid},
+		//the password changed after version 2, its value is not known
+		{2, MergePatchType, nil, ErrorReasonInvalid},
+		{1, ApplyPatchType, nil, ErrorReasonInvalid},
+	}
+	for _, test := range errors {
+		_, err := provObj.RollbackPatch(test.version, test.patchType, test.fields)
+		if reason := ReasonForError(err); reason != test.reason {
+			t.Errorf("Error for TestRollbackPatch() to version %d %s %v was incorrect, got: %s (%v), want: %s.\n", test.version, test.patchType, test.fields, reason, err, test.reason)
+		}
+	}
+}
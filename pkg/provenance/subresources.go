@@ -0,0 +1,122 @@
+package provenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// A write to a subresource that is neither scale nor status. It is kept
+// with the object so the write is not lost, but it does not create a version.
+type AnnotatedEvent struct {
+	Timestamp   string
+	Verb        string
+	Subresource string
+	Actor       string
+	Annotation  string
+}
+
+// Routes an audit event for objectRef.subresource to the lineage it
+// belongs to. The body of such a request describes the subresource
+// (a Scale, an object with a new status), never the parent's spec.
+// Returns the problems found while building the status.
+func processSubresourceEvent(provObj *ProvenanceOfObject, event *Event, timestamp string) []string {
+	subresource := event.ObjectRef.Subresource
+	switch subresource {
+	case "scale":
+		replicas, ok := scaleReplicas(event.RequestObject.Raw)
+		if !ok {
+			recordAnnotatedEvent(provObj, event, timestamp, "scale request without spec.replicas")
+			return nil
+		}
+		recordScale(provObj, event, timestamp, replicas)
+	case "status":
+		var result map[string]interface{}
+		json.Unmarshal(event.RequestObject.Raw, &result)
+		status, ok := result["status"].(map[string]interface{})
+		if !ok {
+			recordAnnotatedEvent(provObj, event, timestamp, "status request without status")
+			return nil
+		}
+		redactSpec(kindForPlural(provObj.ResourcePlural), status)
+		newVersion := len(provObj.StatusHistory) + 1
+		newStatus, skipped := buildSpec(status)
+		newStatus.Version = newVersion
+		newStatus.Timestamp = timestamp
+		provObj.StatusHistory[newVersion] = newStatus
+		recordFieldManagers(provObj.StatusHistory, newVersion, event)
+		return skipped
+	default:
+		recordAnnotatedEvent(provObj, event, timestamp, "write to unsupported subresource")
+	}
+	return nil
+}
+
+func scaleReplicas(requestObjBytes []byte) (int, bool) {
+	var scale struct {
+		Spec struct {
+			Replicas *int `json:"replicas"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(requestObjBytes, &scale); err != nil || scale.Spec.Replicas == nil {
+		return 0, false
+	}
+	return *scale.Spec.Replicas, true
+}
+
+// A scale is a change of replicas on top of the latest spec version.
+func recordScale(provObj *ProvenanceOfObject, event *Event, timestamp string, replicas int) {
+	newVersion := len(provObj.ObjectFullHistory) + 1
+	newSpec := *NewSpec()
+	latest, _ := latestSpec(provObj.ObjectFullHistory)
+	for attribute, data := range latest.AttributeToData {
+		newSpec.AttributeToData[attribute] = data
+	}
+	newSpec.AttributeToData["replicas"] = replicas
+	newSpec.Labels = latest.Labels
+	newSpec.APIVersion = latest.APIVersion
+	newSpec.Kind = latest.Kind
+	newSpec.RawSpec = make(map[string]interface{})
+	for field, value := range latest.RawSpec {
+		newSpec.RawSpec[field] = value
+	}
+	newSpec.RawSpec["replicas"] = replicas
+	newSpec.Version = newVersion
+	newSpec.Timestamp = timestamp
+	provObj.ObjectFullHistory[newVersion] = newSpec
+	countVersion(provObj.ResourcePlural)
+	recordFieldManagers(provObj.ObjectFullHistory, newVersion, event)
+}
+
+func recordAnnotatedEvent(provObj *ProvenanceOfObject, event *Event, timestamp, annotation string) {
+	annotatedEvent := AnnotatedEvent{
+		Timestamp:   timestamp,
+		Verb:        event.Verb,
+		Subresource: event.ObjectRef.Subresource,
+		Actor:       event.User.Username,
+		Annotation:  annotation,
+	}
+	fmt.Printf("Annotated event for %s %s: %v\n", provObj.ResourcePlural, provObj.Name, annotatedEvent)
+	provObj.Events = append(provObj.Events, annotatedEvent)
+}
+
+// Returns the spec with the highest version number.
+func latestSpec(o ObjectLineage) (Spec, bool) {
+	specs := getSpecsInOrder(o)
+	if len(specs) == 0 {
+		return Spec{}, false
+	}
+	return specs[len(specs)-1], true
+}
+
+// Returns the string representation of the annotated events of an object.
+func (p *ProvenanceOfObject) EventsString() string {
+	if len(p.Events) == 0 {
+		return "No subresource events found.\n"
+	}
+	var b strings.Builder
+	for _, e := range p.Events {
+		fmt.Fprintf(&b, "%s: %s %s by %s (%s)\n", e.Timestamp, e.Verb, e.Subresource, e.Actor, e.Annotation)
+	}
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package provenance
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+var subresourceEvents = []string{
+	`{"verb":"create","user":{"username":"system:admin"},"objectRef":{"resource":"postgreses","namespace":"default"},"requestObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"sub-client"},"spec":{"image":"postgres:9.3","replicas":1}},"requestReceivedTimestamp":"2018-08-05T00:16:20.000000Z"}`,
+	`{"verb":"update","user":{"username":"system:admin"},"objectRef":{"resource":"postgreses","namespace":"default","name":"sub-client","subresource":"scale"},"requestObject":{"apiVersion":"autoscaling/v1","kind":"Scale","metadata":{"name":"sub-client"},"spec":{"replicas":4}},"requestReceivedTimestamp":"2018-08-05T00:17:20.000000Z"}`,
+	`{"verb":"update","user":{"username":"postgres-operator"},"objectRef":{"resource":"postgreses","namespace":"default","name":"sub-client","subresource":"status"},"requestObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"sub-client"},"spec":{"image":"postgres:9.3","replicas":4},"status":{"status":"READY"}},"requestReceivedTimestamp":"2018-08-05T00:18:20.000000Z"}`,
+	`{"verb":"create","user":{"username":"system:admin"},"objectRef":{"resource":"postgreses","namespace":"default","name":"sub-client","subresource":"proxy"},"requestObject":{"kind":"Other"},"requestReceivedTimestamp":"2018-08-05T00:19:20.000000Z"}`,
+}
+
+// Tests that a scale becomes a replica change on top of the latest spec,
+// a status write goes to the status history, and anything else is only
+// recorded as an annotated event.
+func TestSubresourceRouting(t *testing.T) {
+	for _, eventJson := range subresourceEvents {
+		var event Event
+		if err := json.Unmarshal([]byte(eventJson), &event); err != nil {
+			t.Fatalf("Could not parse test event: %s", err)
+		}
+		processEvent(&event)
+	}
+	provObj := FindProvenanceObjectByName("sub-client", AllProvenanceObjects)
+	if provObj == nil {
+		t.Fatalf("No provenance recorded for sub-client")
+	}
+
+	history := provObj.ObjectFullHistory.SpecHistory()
+	expected := `Version: 1
+  image: postgres:9.3
+  replicas: 1
+Version: 2
+  image: postgres:9.3
+  replicas: 4
+`
+	if history != expected {
+		t.Errorf("History output for TestSubresourceRouting() was incorrect, got: %s, want: %s.\n", history, expected)
+	}
+
+	statusHistory := provObj.StatusHistory.SpecHistory()
+	expected = `Version: 1
+  status: READY
+`
+	if statusHistory != expected {
+		t.Errorf("Status history output for TestSubresourceRouting() was incorrect, got: %s, want: %s.\n", statusHistory, expected)
+	}
+
+	events := provObj.EventsString()
+	expected = "2018-08-05 00:19:20: create proxy by system:admin (write to unsupported subresource)\n"
+	if events != expected {
+		t.Errorf("Events output for TestSubresourceRouting() was incorrect, got: %s, want: %s.\n", events, expected)
+	}
+}
@@ -0,0 +1,80 @@
+package provenance
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QueryRange is a run of consecutive versions over which a bisect query
+// held. Deletions never satisfy a query, so they end a run.
+type QueryRange struct {
+	// the version that made the query hold
+	Start Spec
+	// the last version of the run
+	Last int
+	// the version that made the query stop holding, nil if it still holds
+	// at the latest version
+	End *Spec
+}
+
+// BisectTransitions returns every run of versions over which the query
+// held, in order. Between two runs are the versions where it did not.
+func (o ObjectLineage) BisectTransitions(argMap map[string]string) ([]QueryRange, error) {
+	node, err := o.compileArgs(argMap)
+	if err != nil {
+		return nil, err
+	}
+	return o.transitions(node), nil
+}
+
+func (o ObjectLineage) transitions(node *queryNode) []QueryRange {
+	ranges := make([]QueryRange, 0)
+	var current *QueryRange
+	for _, spec := range getSpecsInOrder(o) {
+		holds := !spec.Deleted && node.holds(genericSpec(spec))
+		switch {
+		case holds && current == nil:
+			current = &QueryRange{Start: spec, Last: spec.Version}
+		case holds:
+			current.Last = spec.Version
+		case current != nil:
+			end := spec
+			current.End = &end
+			ranges = append(ranges, *current)
+			current = nil
+		}
+	}
+	if current != nil {
+		ranges = append(ranges, *current)
+	}
+	return ranges
+}
+
+// TransitionsString returns the string representation of the runs of a
+// transitions query.
+func TransitionsString(ranges []QueryRange) string {
+	if len(ranges) == 0 {
+		return "The query never held.\n"
+	}
+	var b strings.Builder
+	for _, r := range ranges {
+		fmt.Fprintf(&b, "Holds from version %d (%s)", r.Start.Version, describeChange(r.Start))
+		if r.End == nil {
+			fmt.Fprintf(&b, " through the latest version %d\n", r.Last)
+			continue
+		}
+		fmt.Fprintf(&b, " through version %d, stops at version %d (%s)\n", r.Last, r.End.Version, describeChange(*r.End))
+	}
+	return b.String()
+}
+
+func describeChange(spec Spec) string {
+	description := spec.Timestamp
+	if spec.Actor != "" {
+		description += " by " + spec.Actor
+	}
+	if spec.Deleted {
+		description += ", deleted"
+	}
+	return description
+}
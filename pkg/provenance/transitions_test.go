@@ -0,0 +1,52 @@
+package provenance
+
+import (
+	"testing"
+)
+
+// Tests that a transitions query reports every run of versions over which
+// the query held, ended by a change of the field or by a deletion.
+func TestBisectTransitions(t *testing.T) {
+	objLineage := buildNestedLineage()
+
+	tests := []struct {
+		name  string
+		query map[string]string
+		want  string
+	}{
+		{"stopped holding", map[string]string{"field1": "replicas", "value1": "3"},
+			"Holds from version 2 (2018-08-05 00:11:00) through version 2, stops at version 3 (2018-08-05 00:12:00)\n"},
+		{"ended by the deletion", map[string]string{"field1": "databases", "op1": "contains", "value1": "logging"},
+			"Holds from version 3 (2018-08-05 00:12:00) through version 4, stops at version 5 (2018-08-05 00:20:00 by alice, deleted)\n"},
+		{"stopped holding in a list element", map[string]string{"field1": "users[username=daniel].password", "value1": "p1"},
+			"Holds from version 1 (2018-08-05 00:10:00) through version 3, stops at version 4 (2018-08-05 00:13:00)\n"},
+		{"never held", map[string]string{"field1": "replicas", "op1": "gt", "value1": "10"}, "The query never held.\n"},
+	}
+	for _, test := range tests {
+		ranges, err := objLineage.BisectTransitions(test.query)
+		if err != nil {
+			t.Errorf("Error for TestBisectTransitions() %s was incorrect, got: %v, want: none.\n", test.name, err)
+			continue
+		}
+		if got := TransitionsString(ranges); got != test.want {
+			t.Errorf("Transitions for TestBisectTransitions() %s was incorrect, got: %s, want: %s.\n", test.name, got, test.want)
+		}
+	}
+
+	//the object is created again, the run that starts then is still open
+	recreated := objLineage[3]
+	recreated.Version = 6
+	recreated.Timestamp = "2018-08-05 00:30:00"
+	recreated.Actor = "bob"
+	objLineage[6] = recreated
+	ranges, _ := objLineage.BisectTransitions(map[string]string{"field1": "replicas", "value1": "5"})
+	want := "Holds from version 3 (2018-08-05 00:12:00) through version 4, stops at version 5 (2018-08-05 00:20:00 by alice, deleted)\n" +
+		"Holds from version 6 (2018-08-05 00:30:00 by bob) through the latest version 6\n"
+	if got := TransitionsString(ranges); got != want || len(ranges) != 2 || ranges[1].End != nil {
+		t.Errorf("Transitions for TestBisectTransitions() was incorrect, got: %s, want: %s.\n", got, want)
+	}
+
+	if _, err := objLineage.BisectTransitions(map[string]string{"field1": "replicas", "op1": "gt", "value1": "x"}); ReasonForError(err) != ErrorReasonBadRequest {
+		t.Errorf("Error for TestBisectTransitions() was incorrect, got: %v, want: %s.\n", err, ErrorReasonBadRequest)
+	}
+}
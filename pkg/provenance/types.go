@@ -2,10 +2,21 @@ package provenance
 
 // Used for unmarshalling JSON output from the main API server
 type composition struct {
-	Kind        string   `yaml:"kind"`
-	Plural      string   `yaml:"plural"`
-	Endpoint    string   `yaml:"endpoint"`
-	Composition []string `yaml:"composition"`
+	Kind        string    `yaml:"kind"`
+	Plural      string    `yaml:"plural"`
+	Endpoint    string    `yaml:"endpoint"`
+	Composition []string  `yaml:"composition"`
+	Redact      redaction `yaml:"redact"`
+	// Namespaced, the default, or Cluster
+	Scope string `yaml:"scope"`
+	// short names of the provenance resource of the kind, for discovery
+	ShortNames []string `yaml:"shortNames"`
+}
+
+// Secret fields of a kind, which are hashed before they are stored
+type redaction struct {
+	Fields      []string `yaml:"fields"`
+	KeyPatterns []string `yaml:"keyPatterns"`
 }
 
 // Used for Final output
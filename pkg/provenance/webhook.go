@@ -0,0 +1,29 @@
+package provenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+)
+
+// Source of the events that are posted to the server by the audit webhook
+// backend, the offset of such an event is its number since the start.
+const webhookSource = "webhook"
+
+var webhookOffset int64
+
+// IngestEventList adds the events of an audit EventList, as sent by the
+// audit webhook backend of the kube-apiserver.
+func IngestEventList(body []byte) error {
+	var eventList struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(body, &eventList); err != nil {
+		return fmt.Errorf("Could not parse the audit event list: %s", err)
+	}
+	for _, item := range eventList.Items {
+		offset := atomic.AddInt64(&webhookOffset, 1) - 1
+		ingestEvent(item, webhookSource, offset)
+	}
+	return nil
+}
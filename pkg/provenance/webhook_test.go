@@ -0,0 +1,34 @@
+package provenance
+
+import (
+	"strings"
+	"testing"
+)
+
+// Tests that the items of a posted EventList are ingested, and that broken
+// items become dead letters numbered by their position in the stream.
+func TestIngestEventList(t *testing.T) {
+	deadLetters = &deadLetterStore{letters: make([]DeadLetter, 0)}
+	webhookOffset = 0
+
+	body := `{"kind":"EventList","apiVersion":"audit.k8s.io/v1beta1","items":[` +
+		`{"verb":"create"},` +
+		`{"verb":"create","objectRef":{"resource":"postgreses","namespace":"default","name":"webhook-client"},"requestObject":{"apiVersion":"postgrescontroller.kubeplus/v1","kind":"Postgres","metadata":{"name":"webhook-client"},"spec":{"image":"postgres:9.3"}},"requestReceivedTimestamp":"2018-08-05T00:16:20.000000Z"}` +
+		`]}`
+	if err := IngestEventList([]byte(body)); err != nil {
+		t.Errorf("IngestEventList() for TestIngestEventList() returned an error: %s\n", err)
+	}
+
+	provObj := FindProvenanceObjectByName("webhook-client", AllProvenanceObjects)
+	if provObj == nil || len(provObj.ObjectFullHistory) != 1 {
+		t.Errorf("Lineage for TestIngestEventList() was incorrect, got: %v, want: 1 version.\n", provObj)
+	}
+	expected := "webhook:0 (rejected): Event has no objectRef"
+	if output := DeadLetters(); !strings.Contains(output, expected) {
+		t.Errorf("Dead letters output for TestIngestEventList() is missing %s, got: %s\n", expected, output)
+	}
+
+	if err := IngestEventList([]byte(`{"items":`)); err == nil {
+		t.Errorf("IngestEventList() for TestIngestEventList() accepted a broken event list.\n")
+	}
+}